@@ -1,28 +1,88 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
-	"sync"
 	"syscall"
+	"time"
 
 	"github.com/gordonklaus/portaudio"
-	"github.com/hajimehoshi/ebiten/v2"
+	"golang.org/x/sync/errgroup"
 
 	"oscilloscope/display"
 	"oscilloscope/internal/acquisition"
+	"oscilloscope/internal/analysis"
 	"oscilloscope/internal/audio"
+	"oscilloscope/internal/filter"
+	"oscilloscope/internal/hub"
+	"oscilloscope/internal/mathchan"
+	"oscilloscope/internal/measure"
 	"oscilloscope/internal/memory"
-	"oscilloscope/internal/record"
 	"oscilloscope/internal/source"
 	"oscilloscope/internal/trigger"
 )
 
+// measureDepth is how many records the statistics may fall behind before
+// new ones are dropped.
+const measureDepth = 16
+
+type options struct {
+	stream          source.Stream
+	ringSize        int
+	historyPath     string
+	historyDuration time.Duration
+	a4              float64
+	math            []string
+	filter          filter.Settings
+}
+
 func main() {
+	var opts options
+	flag.IntVar(&opts.stream.SampleRate, "rate", source.DefaultSampleRate, fmt.Sprintf("sample rate in Hz, one of %v", source.SupportedSampleRates))
+	flag.IntVar(&opts.stream.BufferSize, "buffer", source.DefaultBufferSize, "frames per audio callback")
+	flag.IntVar(&opts.stream.Channels, "channels", source.DefaultChannels, "input channels to capture; ch1 drives the trigger")
+	flag.IntVar(&opts.ringSize, "ring-size", 0, "in-memory ring size in samples, a power of two; 0 sizes it to fit a record")
+	flag.StringVar(&opts.historyPath, "history", "", "memory-mapped file for deep history; empty disables it")
+	flag.DurationVar(&opts.historyDuration, "history-duration", 5*time.Minute, "how much history the file keeps")
+	flag.Float64Var(&opts.a4, "a4", analysis.DefaultA4, "tuner reference pitch of A4 in Hz")
+	flag.Func("math", "math channel expression over the input channels, such as \"(ch1-ch2)/2\" or \"integ(ch1)\"; repeatable", func(s string) error {
+		opts.math = append(opts.math, s)
+		return nil
+	})
+	opts.filter = filter.DefaultSettings()
+	flag.Func("filter", "ch1 input filter: off, lowpass, highpass, bandpass, notch, lowshelf or highshelf", func(s string) error {
+		k, err := filter.ParseKind(s)
+		opts.filter.Kind = k
+		return err
+	})
+	flag.Float64Var(&opts.filter.Cutoff, "cutoff", filter.DefaultCutoff, "filter corner or centre frequency in Hz")
+	flag.Float64Var(&opts.filter.Q, "q", filter.DefaultQ, "filter resonance")
+	flag.Float64Var(&opts.filter.GainDB, "shelf-gain", filter.DefaultGainDB, "shelving filter gain in dB")
+	flag.BoolVar(&opts.filter.DCBlock, "dc-block", false, "remove DC from the input")
+	flag.Parse()
+
+	if err := run(opts); err != nil {
+		log.Print(err)
+		os.Exit(1)
+	}
+}
+
+// run wires the pipeline and supervises its runners. The first runner to
+// fail cancels the others, and closing the display stops everything.
+func run(opts options) error {
+	if err := opts.stream.Validate(); err != nil {
+		return err
+	}
+	if opts.a4 <= 0 {
+		return fmt.Errorf("tuner reference %g Hz must be positive", opts.a4)
+	}
+
 	if err := portaudio.Initialize(); err != nil {
-		log.Fatal("PortAudio init:", err)
+		return fmt.Errorf("initialize portaudio: %w", err)
 	}
 	defer func() {
 		if err := portaudio.Terminate(); err != nil {
@@ -30,65 +90,109 @@ func main() {
 		}
 	}()
 
-	var mu sync.Mutex
-	cond := sync.NewCond(&mu)
+	streamDesc, err := audio.Negotiate(opts.stream)
+	if err != nil {
+		return fmt.Errorf("negotiate audio format: %w", err)
+	}
+	if streamDesc != opts.stream {
+		log.Printf("Using %d Hz, %d Hz is not supported by the device", streamDesc.SampleRate, opts.stream.SampleRate)
+	}
 
-	ring := memory.New(memory.MemoryBufferSize)
 	trig := trigger.New()
-	acquirer := acquisition.New(trig)
-
-	done := make(chan struct{})
-	recordCh := make(chan record.Record, 1)
+	acquirer := acquisition.New(trig, streamDesc)
 
-	var closeOnce sync.Once
-	shutdown := func() {
-		closeOnce.Do(func() {
-			close(done)
-		})
+	ringSize := opts.ringSize
+	if ringSize == 0 {
+		ringSize = max(memory.MemoryBufferSize, memory.NextPowerOfTwo(acquirer.SamplesPerRecord()))
 	}
-
-	stream, err := audio.NewPortAudioRunner(
-		ring,
-		cond,
-		source.SampleRate,
-		source.BufferSize,
-	)
-	if err != nil {
-		log.Fatal("Audio stream:", err)
+	if !memory.IsPowerOfTwo(ringSize) {
+		return fmt.Errorf("ring size %d is not a power of two", ringSize)
 	}
-
-	if err := stream.Start(); err != nil {
-		log.Fatal("Stream start:", err)
+	if ringSize < acquirer.SamplesPerRecord() {
+		return fmt.Errorf("ring size %d is smaller than a record (%d samples)", ringSize, acquirer.SamplesPerRecord())
 	}
-	defer func() {
-		if err := stream.Stop(); err != nil {
-			log.Printf("Stream stop: %v", err)
+
+	ring := memory.New(ringSize)
+	notifier := memory.NewNotifier()
+	deep := &memory.Deep{Recent: ring}
+
+	if opts.historyPath != "" {
+		size := memory.NextPowerOfTwo(streamDesc.Samples(opts.historyDuration))
+		history, err := memory.NewMapped(opts.historyPath, size)
+		if err != nil {
+			return err
 		}
-	}()
+		defer func() {
+			if err := history.Close(); err != nil {
+				log.Printf("History close: %v", err)
+			}
+		}()
+		deep.History = history.Ring
+	}
 
-	acquirerRunner := acquisition.NewRunner(ring, acquirer, cond, recordCh, done)
-	go acquirerRunner.Run()
+	processor := acquisition.NewProcessor(acquirer)
+	segments := acquisition.NewSegmentStore(acquisition.DefaultSegmentCount)
 
-	sigch := make(chan os.Signal, 1)
-	signal.Notify(sigch, os.Interrupt, syscall.SIGTERM)
+	records := hub.New()
+	displaySub := records.Subscribe("display", 1, hub.LatestOnly)
+	measureSub := records.Subscribe("measure", measureDepth, hub.BoundedQueue)
+	stats := measure.NewStatistics()
 
-	go func() {
-		<-sigch
-		fmt.Println("\nShutting down...")
-		shutdown()
-		cond.Broadcast()
-	}()
+	// ch1 keeps the ring everything else reads; math channels read the rest
+	rings := []*memory.Ring{ring}
+	for range streamDesc.Channels - 1 {
+		rings = append(rings, memory.New(ringSize))
+	}
+
+	inputFilter := filter.NewStage(streamDesc.SampleRate, opts.filter)
+	stream, err := audio.NewPortAudioRunner(rings, notifier, streamDesc, inputFilter)
+	if err != nil {
+		return fmt.Errorf("open audio stream: %w", err)
+	}
+
+	tuner := analysis.NewTunerRunner(ring, acquirer, opts.a4)
 
 	cfg := display.DefaultConfig()
-	d, err := display.New(cfg, acquirer, recordCh, done, shutdown)
+	for _, src := range opts.math {
+		e, err := mathchan.Parse(src)
+		if err == nil {
+			err = e.Check(streamDesc.Channels)
+		}
+		if err != nil {
+			return fmt.Errorf("math channel %q: %w", src, err)
+		}
+		cfg.Math = append(cfg.Math, e)
+	}
+	cfg.Siblings = rings[1:]
+	d, err := display.New(cfg, acquirer, processor, deep, segments, stats, tuner, inputFilter, displaySub.C())
 	if err != nil {
-		log.Fatal("Display init:", err)
+		return fmt.Errorf("init display: %w", err)
 	}
 
-	if err := ebiten.RunGame(d); err != nil && err != ebiten.Termination {
-		log.Fatal(err)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	g, ctx := errgroup.WithContext(ctx)
+
+	acquirerRunner := acquisition.NewRunner(ring, acquirer, processor, segments, notifier, records)
+	acquirerRunner.Siblings = rings[1:]
+	g.Go(func() error { return acquirerRunner.Run(ctx) })
+	g.Go(func() error { return stream.Run(ctx) })
+
+	statsRunner := measure.NewStatsRunner(measureSub.C(), stats)
+	g.Go(func() error { return statsRunner.Run(ctx) })
+	g.Go(func() error { return tuner.Run(ctx) })
+
+	if deep.History != nil {
+		historyRunner := &memory.HistoryRunner{Recent: ring, History: deep.History, Notifier: notifier}
+		g.Go(func() error { return historyRunner.Run(ctx) })
 	}
 
-	shutdown()
-	cond.Broadcast()
+	displayErr := d.Run(ctx)
+	stop()
+
+	if err := g.Wait(); err != nil {
+		return err
+	}
+	return displayErr
 }
@@ -0,0 +1,65 @@
+package display
+
+import (
+	"image/color"
+	"math"
+)
+
+type ColorMap int
+
+const (
+	Viridis ColorMap = iota
+	Magma
+	PhosphorGreen
+	colorMapCount
+)
+
+func (m ColorMap) String() string {
+	switch m {
+	case Magma:
+		return "magma"
+	case PhosphorGreen:
+		return "phosphor"
+	default:
+		return "viridis"
+	}
+}
+
+// Next returns the colour map after m, wrapping around.
+func (m ColorMap) Next() ColorMap {
+	return (m + 1) % colorMapCount
+}
+
+// Evenly spaced stops of each map, from lowest to highest level.
+var colorMapStops = [colorMapCount][]color.RGBA{
+	Viridis: {
+		{0x44, 0x01, 0x54, 0xff}, {0x47, 0x2c, 0x7a, 0xff}, {0x3b, 0x51, 0x8b, 0xff},
+		{0x2c, 0x71, 0x8e, 0xff}, {0x21, 0x90, 0x8d, 0xff}, {0x27, 0xad, 0x81, 0xff},
+		{0x5c, 0xc8, 0x63, 0xff}, {0xaa, 0xdc, 0x32, 0xff}, {0xfd, 0xe7, 0x25, 0xff},
+	},
+	Magma: {
+		{0x00, 0x00, 0x04, 0xff}, {0x1c, 0x10, 0x44, 0xff}, {0x4f, 0x12, 0x7b, 0xff},
+		{0x81, 0x25, 0x81, 0xff}, {0xb5, 0x36, 0x7a, 0xff}, {0xe5, 0x50, 0x64, 0xff},
+		{0xfb, 0x87, 0x61, 0xff}, {0xfe, 0xc2, 0x87, 0xff}, {0xfc, 0xfd, 0xbf, 0xff},
+	},
+	PhosphorGreen: {
+		{0x00, 0x00, 0x00, 0xff}, {0x00, 0x50, 0x00, 0xff},
+		{0x40, 0xff, 0x40, 0xff}, {0xc8, 0xff, 0xc8, 0xff},
+	},
+}
+
+// At returns the colour for t in [0, 1], interpolating between stops.
+func (m ColorMap) At(t float64) color.RGBA {
+	stops := colorMapStops[Viridis]
+	if m >= 0 && m < colorMapCount {
+		stops = colorMapStops[m]
+	}
+
+	pos := math.Max(0, math.Min(1, t)) * float64(len(stops)-1)
+	i := min(int(pos), len(stops)-2)
+	f := pos - float64(i)
+
+	a, b := stops[i], stops[i+1]
+	lerp := func(x, y uint8) uint8 { return uint8(float64(x) + (float64(y)-float64(x))*f + 0.5) }
+	return color.RGBA{R: lerp(a.R, b.R), G: lerp(a.G, b.G), B: lerp(a.B, b.B), A: 0xff}
+}
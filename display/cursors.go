@@ -0,0 +1,254 @@
+package display
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const (
+	// cursorGrab is how close in pixels a click must land to pick up a
+	// cursor.
+	cursorGrab = 8.0
+	// cursorStep is how far in pixels a key press moves the selected
+	// cursor; holding Shift moves it ten times as far.
+	cursorStep = 1.0
+	// cursorDash is the length in pixels of each dash of a cursor line.
+	cursorDash = 6.0
+)
+
+var (
+	timeCursorColor = color.RGBA{R: 255, G: 200, B: 60, A: 200}
+	ampCursorColor  = color.RGBA{R: 60, G: 200, B: 255, A: 200}
+)
+
+// Cursor identifies one of the four cursors.
+type Cursor int
+
+const (
+	CursorT1 Cursor = iota
+	CursorT2
+	CursorV1
+	CursorV2
+	cursorCount
+)
+
+var cursorNames = [cursorCount]string{"T1", "T2", "V1", "V2"}
+
+func (c Cursor) String() string { return cursorNames[c] }
+
+// cursors holds positions independent of the window size: time cursors as
+// a fraction of the trace width and amplitude cursors in sample units, so
+// they map through the same functions as the trace on every frame.
+type cursors struct {
+	enabled  bool
+	snap     bool
+	selected Cursor
+	dragging Cursor // cursorCount when nothing is being dragged
+
+	t [2]float64
+	v [2]float64
+}
+
+func newCursors() cursors {
+	return cursors{
+		dragging: cursorCount,
+		t:        [2]float64{0.25, 0.75},
+		v:        [2]float64{-0.5, 0.5},
+	}
+}
+
+func (d *Display) handleCursorKeys() {
+	c := &d.cursors
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyC) {
+		c.enabled = !c.enabled
+	}
+	if !c.enabled {
+		return
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyTab) {
+		c.selected = (c.selected + 1) % cursorCount
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyS) {
+		c.snap = !c.snap
+	}
+
+	step := cursorStep
+	if ebiten.IsKeyPressed(ebiten.KeyShift) {
+		step *= 10
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyComma) {
+		d.moveCursor(c.selected, -step)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyPeriod) {
+		d.moveCursor(c.selected, step)
+	}
+
+	d.handleCursorMouse()
+
+	if c.snap {
+		d.snapCursors()
+	}
+}
+
+// moveCursor nudges a cursor by px pixels, right for time cursors and up
+// for amplitude cursors.
+func (d *Display) moveCursor(cur Cursor, px float64) {
+	c := &d.cursors
+	switch cur {
+	case CursorT1, CursorT2:
+		i := cur - CursorT1
+		c.t[i] = clamp01(c.t[i] + px/float64(d.layoutWidth))
+	case CursorV1, CursorV2:
+		i := cur - CursorV1
+		y := d.sampleToScreenY(c.v[i]) - px
+		c.v[i] = d.screenToSample(y)
+	}
+}
+
+func (d *Display) handleCursorMouse() {
+	c := &d.cursors
+	mx, my := ebiten.CursorPosition()
+	x, y := float64(mx), float64(my)
+	w := float64(d.layoutWidth)
+
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		best := cursorGrab
+		for cur := range cursorCount {
+			var dist float64
+			if cur < CursorV1 {
+				dist = math.Abs(x - c.t[cur-CursorT1]*w)
+			} else {
+				dist = math.Abs(y - d.sampleToScreenY(c.v[cur-CursorV1]))
+			}
+			if dist <= best {
+				best = dist
+				c.dragging = cur
+			}
+		}
+		if c.dragging != cursorCount {
+			c.selected = c.dragging
+		}
+	}
+
+	if !ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		c.dragging = cursorCount
+		return
+	}
+
+	switch c.dragging {
+	case CursorT1, CursorT2:
+		c.t[c.dragging-CursorT1] = clamp01(x / w)
+	case CursorV1, CursorV2:
+		c.v[c.dragging-CursorV1] = d.screenToSample(y)
+	}
+}
+
+// snapCursors moves each amplitude cursor onto the trace at the time cursor
+// with the same number.
+func (d *Display) snapCursors() {
+	for i := range d.cursors.t {
+		if v, ok := d.traceAt(d.cursors.t[i]); ok {
+			d.cursors.v[i] = v
+		}
+	}
+}
+
+// traceAt returns the record sample drawn at fraction f of the screen
+// width, the same sample the sweep deposits there.
+func (d *Display) traceAt(f float64) (float64, bool) {
+	rec := d.currentRecord
+	if rec == nil || len(rec.Samples) == 0 {
+		return 0, false
+	}
+
+	w := float64(d.layoutWidth)
+	return float64(rec.Samples[toSampleIdx(f*w, w, len(rec.Samples))]), true
+}
+
+// timeAt returns the time at fraction f of the screen width relative to
+// the trigger point.
+func (d *Display) timeAt(f float64) (float64, bool) {
+	rec := d.currentRecord
+	if rec == nil || len(rec.Samples) == 0 || rec.SampleRate == 0 {
+		return 0, false
+	}
+
+	w := float64(d.layoutWidth)
+	idx := toSampleIdx(f*w, w, len(rec.Samples))
+	return (float64(idx-rec.TriggerIndex) - rec.TriggerOffset) / float64(rec.SampleRate), true
+}
+
+func (d *Display) drawCursors(dst *ebiten.Image) {
+	if !d.cursors.enabled {
+		return
+	}
+
+	w, h := float32(d.layoutWidth), float32(d.layoutHeight)
+	for i, t := range d.cursors.t {
+		x := float32(t) * w
+		drawDashed(dst, x, 0, x, h, timeCursorColor, d.cursors.selected == CursorT1+Cursor(i))
+	}
+	for i, v := range d.cursors.v {
+		y := float32(d.sampleToScreenY(v))
+		drawDashed(dst, 0, y, w, y, ampCursorColor, d.cursors.selected == CursorV1+Cursor(i))
+	}
+}
+
+func (d *Display) cursorLabel() string {
+	c := d.cursors
+	var b strings.Builder
+
+	b.WriteString("CURSORS")
+	if c.snap {
+		b.WriteString(" snap")
+	}
+	fmt.Fprintf(&b, " [%s]", c.selected)
+
+	t1, ok1 := d.timeAt(c.t[0])
+	t2, ok2 := d.timeAt(c.t[1])
+	if ok1 && ok2 {
+		dt := t2 - t1
+		fmt.Fprintf(&b, "\nT1 %s  T2 %s  dT %s  1/dT %s",
+			formatSI(t1, "s"), formatSI(t2, "s"), formatSI(dt, "s"), formatSI(1/math.Abs(dt), "Hz"))
+	}
+
+	for i := range c.t {
+		if v, ok := d.traceAt(c.t[i]); ok {
+			fmt.Fprintf(&b, "\n@T%d %.3f FS", i+1, v)
+		}
+	}
+
+	fmt.Fprintf(&b, "\nV1 %.3f  V2 %.3f  dV %.3f FS", c.v[0], c.v[1], c.v[1]-c.v[0])
+	return b.String()
+}
+
+func drawDashed(dst *ebiten.Image, x0, y0, x1, y1 float32, clr color.RGBA, bold bool) {
+	width := float32(1)
+	if bold {
+		width = 2
+	}
+
+	dx, dy := x1-x0, y1-y0
+	length := float32(math.Hypot(float64(dx), float64(dy)))
+	if length == 0 {
+		return
+	}
+	ux, uy := dx/length, dy/length
+
+	for s := float32(0); s < length; s += 2 * cursorDash {
+		e := min(s+cursorDash, length)
+		vector.StrokeLine(dst, x0+ux*s, y0+uy*s, x0+ux*e, y0+uy*e, width, clr, false)
+	}
+}
+
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
@@ -1,9 +1,12 @@
 package display
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"image/color"
 	"math"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
@@ -11,13 +14,24 @@ import (
 
 	"oscilloscope/display/shaders"
 	"oscilloscope/internal/acquisition"
+	"oscilloscope/internal/analysis"
+	"oscilloscope/internal/filter"
+	"oscilloscope/internal/mathchan"
+	"oscilloscope/internal/measure"
+	"oscilloscope/internal/memory"
 	"oscilloscope/internal/record"
+	"oscilloscope/internal/spectrum"
 )
 
 const (
 	beamSpriteRadius = 4.0
 	beamBrightness   = 0.9
 	subsampleStep    = 0.25
+	holdOffStep      = 5 * time.Millisecond
+	peakStep         = 1.0
+	envelopeAlpha    = 0.25
+	gridCols         = 10
+	gridRows         = 8
 )
 
 type Config struct {
@@ -28,6 +42,15 @@ type Config struct {
 	PhosphorThreshold float64
 	BlurIntensity     float64
 	BlurRadius        float64
+	RollDuration      time.Duration
+	Overlay           OverlayConfig
+	FFTSize           int
+	FFTWindow         spectrum.Window
+	ColorMap          ColorMap
+	WaterfallRange    float64
+	Afterglow         bool
+	Math              []*mathchan.Expr
+	Siblings          []*memory.Ring // ch2 and up, read by math channels in roll mode
 	CRTConfig         shader.CRTConfig
 }
 
@@ -38,15 +61,27 @@ func DefaultConfig() *Config {
 		PhosphorThreshold: 0.1,
 		BlurIntensity:     6.0,
 		BlurRadius:        6.0,
+		RollDuration:      5 * time.Second,
+		Overlay:           DefaultOverlayConfig(),
+		FFTSize:           spectrum.DefaultSize,
+		FFTWindow:         spectrum.Hann,
+		ColorMap:          Viridis,
+		WaterfallRange:    100,
 		CRTConfig:         shader.DefaultCRTConfig(),
 	}
 }
 
 type Display struct {
-	acquirer *acquisition.Acquirer
-	recordCh <-chan record.Record
-	done     <-chan struct{}
-	shutdown func()
+	acquirer  *acquisition.Acquirer
+	processor *acquisition.Processor
+	ring      *memory.Ring
+	siblings  []*memory.Ring
+	deep      *memory.Deep
+	segments  *acquisition.SegmentStore
+	stats     *measure.Statistics
+	tuner     *analysis.TunerRunner
+	recordCh  <-chan record.Record
+	done      <-chan struct{}
 
 	layoutWidth   int
 	layoutHeight  int
@@ -71,19 +106,79 @@ type Display struct {
 	crtConfig shader.CRTConfig
 	crtCanvas *ebiten.Image
 
+	segmentCanvas *ebiten.Image
+
+	overlay       OverlayConfig
+	overlayCanvas *ebiten.Image
+
+	cursors cursors
+
+	showTuner bool
+
+	autosetStatus string
+
+	filter *filter.Stage
+
+	math []*mathTrace
+
+	analyzer      *spectrum.Analyzer
+	spectrumFrame spectrum.Frame
+	spectrumPeaks []spectrum.Peak
+	spectrumLog   bool
+
+	waterfall        *spectrum.Analyzer
+	waterfallA       *ebiten.Image
+	waterfallB       *ebiten.Image
+	waterfallLine    *ebiten.Image
+	waterfallPix     []byte
+	waterfallBuf     []float32
+	waterfallNext    int
+	waterfallRunning bool
+	waterfallRange   float64
+	colorMap         ColorMap
+	afterglow        bool
+
 	currentRecord *record.Record
 	sweeping      bool
 	sweepPixelX   float64
 	prevPixelX    float64
 	prevPixelY    float64
+
+	prevPeakTop    float64
+	prevPeakBottom float64
+
+	envelopeMin   []float32
+	envelopeMax   []float32
+	envelopeColor color.RGBA
+
+	rollDuration time.Duration
+	rolling      bool
+	rollNext     int
+	rollBuf      []float32
+	siblingBufs  [][]float32
+	roll         rollColumns
+	rollCount    int
+	rollOffset   time.Duration
+	rollAnchor   int
+	historyView  historyView
+	historyMin   []float32
+	historyMax   []float32
+
+	segmentIndex   int
+	segmentOverlay bool
+	segmentView    segmentView
 }
 
 func New(
 	cfg *Config,
 	acquirer *acquisition.Acquirer,
+	processor *acquisition.Processor,
+	deep *memory.Deep,
+	segments *acquisition.SegmentStore,
+	stats *measure.Statistics,
+	tuner *analysis.TunerRunner,
+	inputFilter *filter.Stage,
 	recordCh <-chan record.Record,
-	done <-chan struct{},
-	shutdown func(),
 ) (*Display, error) {
 	if cfg == nil {
 		cfg = DefaultConfig()
@@ -107,10 +202,15 @@ func New(
 	w, h := ebiten.Monitor().Size()
 
 	d := &Display{
-		acquirer: acquirer,
-		recordCh: recordCh,
-		done:     done,
-		shutdown: shutdown,
+		acquirer:  acquirer,
+		processor: processor,
+		ring:      deep.Recent,
+		deep:      deep,
+		segments:  segments,
+		stats:     stats,
+		tuner:     tuner,
+		filter:    inputFilter,
+		recordCh:  recordCh,
 
 		layoutWidth:  w,
 		layoutHeight: h,
@@ -120,6 +220,12 @@ func New(
 		blurCanvas:   ebiten.NewImage(w, h),
 		crtCanvas:    ebiten.NewImage(w, h),
 
+		segmentCanvas: ebiten.NewImage(w, h),
+		overlayCanvas: ebiten.NewImage(w, h),
+		waterfallA:    ebiten.NewImage(w, h),
+		waterfallB:    ebiten.NewImage(w, h),
+		waterfallLine: ebiten.NewImage(w, 1),
+
 		decayShader: decayShader,
 		crtShader:   crtShader,
 		blurShader:  blurShader,
@@ -133,8 +239,22 @@ func New(
 		phosphorThreshold: cfg.PhosphorThreshold,
 		blurIntensity:     cfg.BlurIntensity,
 		blurRadius:        cfg.BlurRadius,
+		envelopeColor:     scaleColor(cfg.Phosphor.BeamColor, envelopeAlpha),
+		rollDuration:      cfg.RollDuration,
+		overlay:           cfg.Overlay,
+		cursors:           newCursors(),
+		analyzer:          spectrum.NewAnalyzer(cfg.FFTSize, cfg.FFTWindow),
+		spectrumLog:       true,
+		waterfall:         spectrum.NewAnalyzer(cfg.FFTSize, cfg.FFTWindow),
+		waterfallRange:    cfg.WaterfallRange,
+		colorMap:          cfg.ColorMap,
+		afterglow:         cfg.Afterglow,
+		math:              newMathTraces(cfg.Math),
+		siblings:          cfg.Siblings,
 	}
 
+	acquirer.SetBuckets(w)
+
 	ebiten.SetWindowSize(w, h)
 	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
 	ebiten.SetWindowTitle(cfg.WindowTitle)
@@ -142,12 +262,45 @@ func New(
 	return d, nil
 }
 
+// Run drives the game loop on the calling goroutine, which must be the main
+// one, until Escape is pressed or ctx is cancelled.
+func (d *Display) Run(ctx context.Context) error {
+	d.done = ctx.Done()
+
+	if err := ebiten.RunGame(d); err != nil && !errors.Is(err, ebiten.Termination) {
+		return fmt.Errorf("display: %w", err)
+	}
+	return nil
+}
+
 func (d *Display) Update() error {
+	select {
+	case <-d.done:
+		return ebiten.Termination
+	default:
+	}
+
 	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
-		d.shutdown()
 		return ebiten.Termination
 	}
 
+	if inpututil.IsKeyJustPressed(ebiten.KeyM) {
+		d.acquirer.SetMode(d.acquirer.GetMode().Next())
+	}
+
+	d.handleHoldOffKeys()
+	d.handleAverageKeys()
+	d.handleEnvelopeKeys()
+	d.handleSegmentKeys()
+	d.handleOverlayKeys()
+	d.handleCursorKeys()
+	d.handleTunerKeys()
+	d.handleFilterKeys()
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyF) {
+		d.autoset()
+	}
+
 	d.phosphorB.Clear()
 
 	shaderOp := &ebiten.DrawRectShaderOptions{}
@@ -160,14 +313,48 @@ func (d *Display) Update() error {
 	d.phosphorB.DrawRectShader(d.layoutWidth, d.layoutHeight, d.decayShader, shaderOp)
 	d.phosphorA, d.phosphorB = d.phosphorB, d.phosphorA
 
+	if d.acquirer.GetMode() == acquisition.ModeRoll {
+		d.sweeping = false
+		d.updateRoll()
+		return nil
+	}
+	d.rolling = false
+
+	if d.acquirer.GetMode() == acquisition.ModeSegmented {
+		d.sweeping = false
+		d.updateSegments()
+		return nil
+	}
+
+	if d.acquirer.GetMode() == acquisition.ModeWaterfall {
+		d.sweeping = false
+		d.handleSpectrumKeys()
+		d.handleWaterfallKeys()
+		d.updateWaterfall()
+		return nil
+	}
+	d.waterfallRunning = false
+
+	if d.acquirer.GetMode() == acquisition.ModeSpectrum {
+		d.sweeping = false
+		d.handleSpectrumKeys()
+		d.updateSpectrum()
+		return nil
+	}
+
 	if !d.sweeping {
 		select {
 		case rec, ok := <-d.recordCh:
 			if ok {
 				d.currentRecord = &rec
+				d.updateMath(rec)
+				d.envelopeMin = rec.EnvelopeMin
+				d.envelopeMax = rec.EnvelopeMax
 				d.sweepPixelX = 0
 				d.prevPixelX = 0
 				d.prevPixelY = float64(d.layoutHeight) / 2
+				d.prevPeakTop = d.prevPixelY
+				d.prevPeakBottom = d.prevPixelY
 				d.sweeping = true
 			}
 		default:
@@ -184,6 +371,13 @@ func (d *Display) Update() error {
 
 func (d *Display) Draw(screen *ebiten.Image) {
 	d.crtCanvas.Fill(d.phosphor.Background)
+	d.drawEnvelope(d.crtCanvas)
+	switch d.acquirer.GetMode() {
+	case acquisition.ModeSpectrum:
+		d.drawSpectrum(d.crtCanvas)
+	case acquisition.ModeWaterfall:
+		d.drawWaterfall(d.crtCanvas)
+	}
 	d.crtCanvas.DrawImage(d.phosphorA, &ebiten.DrawImageOptions{Blend: ebiten.BlendLighter})
 
 	// Horizontal pass: phosphorA → blurCanvasH
@@ -202,6 +396,10 @@ func (d *Display) Draw(screen *ebiten.Image) {
 
 	d.crtCanvas.DrawImage(d.blurCanvas, &ebiten.DrawImageOptions{Blend: ebiten.BlendLighter})
 	drawGrid(d.crtCanvas, d.layoutWidth, d.layoutHeight)
+	d.drawCursors(d.crtCanvas)
+	if d.overlay.Curved {
+		d.drawOverlay(d.crtCanvas)
+	}
 	screen.DrawRectShader(d.layoutWidth, d.layoutHeight, d.crtShader, &ebiten.DrawRectShaderOptions{
 		Images: [4]*ebiten.Image{d.crtCanvas},
 		Uniforms: map[string]any{
@@ -213,12 +411,256 @@ func (d *Display) Draw(screen *ebiten.Image) {
 			"ColorTint":           d.crtConfig.ColorTint[:],
 		},
 	})
+	if !d.overlay.Curved {
+		d.drawOverlay(screen)
+	}
 }
 
 func (d *Display) Layout(outsideWidth, outsideHeight int) (int, int) {
 	return d.layoutWidth, d.layoutHeight
 }
 
+// handleHoldOffKeys maps H to the holdoff mode toggle and the up/down
+// arrows to the holdoff value of the current mode.
+func (d *Display) handleHoldOffKeys() {
+	if inpututil.IsKeyJustPressed(ebiten.KeyH) {
+		if d.acquirer.GetHoldOffMode() == acquisition.HoldOffEvents {
+			d.acquirer.SetHoldOffMode(acquisition.HoldOffTime)
+		} else {
+			d.acquirer.SetHoldOffMode(acquisition.HoldOffEvents)
+		}
+	}
+
+	delta := 0
+	if inpututil.IsKeyJustPressed(ebiten.KeyArrowUp) {
+		delta++
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyArrowDown) {
+		delta--
+	}
+	if delta == 0 {
+		return
+	}
+
+	if d.acquirer.GetHoldOffMode() == acquisition.HoldOffEvents {
+		d.acquirer.AdjustHoldOffEvents(delta)
+	} else {
+		d.acquirer.AdjustHoldOff(time.Duration(delta) * holdOffStep)
+	}
+}
+
+// handleAverageKeys maps K to the averaging kind toggle and the bracket
+// keys to halving or doubling the average count or high-res width.
+func (d *Display) handleAverageKeys() {
+	if inpututil.IsKeyJustPressed(ebiten.KeyK) {
+		if d.acquirer.GetAverageKind() == acquisition.AverageBlock {
+			d.acquirer.SetAverageKind(acquisition.AverageExponential)
+		} else {
+			d.acquirer.SetAverageKind(acquisition.AverageBlock)
+		}
+	}
+
+	scale := func(n int) int {
+		if inpututil.IsKeyJustPressed(ebiten.KeyBracketRight) {
+			return n * 2
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyBracketLeft) {
+			return n / 2
+		}
+		return n
+	}
+
+	switch d.acquirer.GetMode() {
+	case acquisition.ModeAverage:
+		d.acquirer.SetAverageCount(scale(d.acquirer.GetAverageCount()))
+	case acquisition.ModeHighRes:
+		d.acquirer.SetHighResWidth(scale(d.acquirer.GetHighResWidth()))
+	case acquisition.ModeEnvelope:
+		if hold := d.acquirer.GetEnvelopeHold(); hold > 0 {
+			d.acquirer.SetEnvelopeHold(max(scale(hold), 1))
+		}
+	case acquisition.ModeRoll:
+		if inpututil.IsKeyJustPressed(ebiten.KeyBracketRight) {
+			d.scaleRollDuration(2)
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyBracketLeft) {
+			d.scaleRollDuration(0.5)
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyPageUp) {
+			d.scrollRoll(1)
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyPageDown) {
+			d.scrollRoll(-1)
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyEnd) {
+			d.rollOffset = 0
+		}
+	}
+}
+
+// handleEnvelopeKeys maps R to clearing the envelope, averages and segment
+// store, and I to toggling infinite envelope hold.
+func (d *Display) handleEnvelopeKeys() {
+	if inpututil.IsKeyJustPressed(ebiten.KeyR) {
+		d.processor.Reset()
+		d.segments.Clear()
+		d.segmentIndex = 0
+		d.stats.Reset()
+		d.analyzer.Reset()
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyI) {
+		if d.acquirer.GetEnvelopeHold() == 0 {
+			d.acquirer.SetEnvelopeHold(acquisition.DefaultEnvelopeHold)
+		} else {
+			d.acquirer.SetEnvelopeHold(0)
+		}
+	}
+}
+
+// handleSegmentKeys maps the left/right arrows to stepping through the
+// captured segments and O to overlaying all of them.
+func (d *Display) handleSegmentKeys() {
+	if d.acquirer.GetMode() != acquisition.ModeSegmented {
+		return
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyArrowLeft) {
+		d.stepSegment(-1)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyArrowRight) {
+		d.stepSegment(1)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyO) {
+		d.segmentOverlay = !d.segmentOverlay
+	}
+}
+
+// autoset fits the vertical, trigger and timebase settings to the signal in
+// the ring and starts the acquisition over.
+func (d *Display) autoset() {
+	sig, err := analysis.Autoset(d.ring, d.acquirer)
+	switch {
+	case errors.Is(err, analysis.ErrNoPeriod):
+		d.autosetStatus = fmt.Sprintf("AUTOSET %.3f FS pk-pk, no period found", sig.Max-sig.Min)
+	case err != nil:
+		d.autosetStatus = fmt.Sprintf("AUTOSET failed: %v", err)
+		return
+	default:
+		d.autosetStatus = fmt.Sprintf("AUTOSET %.3f FS pk-pk, %s", sig.Max-sig.Min, formatSI(1/sig.Period.Seconds(), "Hz"))
+	}
+
+	d.processor.Reset()
+	d.envelopeMin, d.envelopeMax = nil, nil
+}
+
+func (d *Display) handleTunerKeys() {
+	if inpututil.IsKeyJustPressed(ebiten.KeyN) {
+		d.showTuner = !d.showTuner
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyY) {
+		d.tuner.CycleHoldOffPeriods()
+	}
+}
+
+func (d *Display) tunerLabel() string {
+	label := "TUNER ---"
+	if p, ok := d.tuner.Latest(); ok {
+		label = fmt.Sprintf("TUNER %s  %s  A4=%g Hz", p.Note, formatSI(p.Frequency, "Hz"), d.tuner.A4)
+	}
+	if n := d.tuner.HoldOffPeriods.Load(); n > 0 {
+		label += fmt.Sprintf("\nHOLDOFF LOCK %d periods", n)
+	}
+	return label
+}
+
+func (d *Display) handleOverlayKeys() {
+	if inpututil.IsKeyJustPressed(ebiten.KeyP) {
+		d.overlay.Placement = d.overlay.Placement.Next()
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyT) {
+		d.overlay.Curved = !d.overlay.Curved
+	}
+}
+
+func (d *Display) statusText() string {
+	text := fmt.Sprintf("MODE %s\n%s\n%s", d.modeLabel(), d.holdOffLabel(), d.settingsLabel())
+
+	if st := d.ring.Stats(); st.Gaps+st.Rewinds+st.Overruns > 0 {
+		text += fmt.Sprintf("\nRING gaps %d (%d samples) rewinds %d overruns %d",
+			st.Gaps, st.GapSamples, st.Rewinds, st.Overruns)
+	}
+	if d.autosetStatus != "" {
+		text += "\n" + d.autosetStatus
+	}
+	if label := d.filterLabel(); label != "" {
+		text += "\n" + label
+	}
+	if d.showTuner {
+		text += "\n\n" + d.tunerLabel()
+	}
+	if len(d.math) > 0 {
+		text += "\n\n" + d.mathLabel()
+	}
+	if d.cursors.enabled {
+		text += "\n\n" + d.cursorLabel()
+	}
+	if d.acquirer.GetMode() == acquisition.ModeSpectrum && len(d.spectrumPeaks) > 0 {
+		text += "\n\n" + d.peaksLabel()
+	}
+	if len(d.overlay.Measurements) > 0 {
+		text += "\n\n" + d.measurementsLabel()
+	}
+	return text
+}
+
+func (d *Display) modeLabel() string {
+	mode := d.acquirer.GetMode()
+	switch mode {
+	case acquisition.ModeAverage:
+		return fmt.Sprintf("%s %s x%d", mode, d.acquirer.GetAverageKind(), d.acquirer.GetAverageCount())
+	case acquisition.ModeHighRes:
+		return fmt.Sprintf("%s x%d", mode, d.acquirer.GetHighResWidth())
+	case acquisition.ModeEnvelope:
+		if hold := d.acquirer.GetEnvelopeHold(); hold > 0 {
+			return fmt.Sprintf("%s x%d", mode, hold)
+		}
+		return fmt.Sprintf("%s infinite", mode)
+	case acquisition.ModeRoll:
+		if d.rollOffset > 0 {
+			return fmt.Sprintf("%s %s -%s", mode, d.rollDuration, d.rollOffset)
+		}
+		return fmt.Sprintf("%s %s", mode, d.rollDuration)
+	case acquisition.ModeSpectrum:
+		return fmt.Sprintf("%s %s", mode, d.spectrumLabel())
+	case acquisition.ModeWaterfall:
+		label := fmt.Sprintf("%s %d %s %s %.0f dB", mode, d.analyzer.Size, d.analyzer.Window, d.colorMap, d.waterfallRange)
+		if d.afterglow {
+			label += " afterglow"
+		}
+		return label
+	case acquisition.ModeSegmented:
+		n := d.segments.Len()
+		if d.segmentOverlay || n == 0 {
+			return fmt.Sprintf("%s %d/%d overlay", mode, n, d.segments.Capacity())
+		}
+		seg, _ := d.segments.At(d.segmentIndex)
+		return fmt.Sprintf("%s %d/%d #%d @%d %s", mode, n, d.segments.Capacity(),
+			d.segmentIndex+1, seg.TriggerIndex, seg.Time.Format("15:04:05.000"))
+	default:
+		return mode.String()
+	}
+}
+
+func (d *Display) holdOffLabel() string {
+	if d.acquirer.GetHoldOffMode() == acquisition.HoldOffEvents {
+		return fmt.Sprintf("HOLDOFF %d events", d.acquirer.GetHoldOffEvents())
+	}
+	return fmt.Sprintf("HOLDOFF %s", d.acquirer.GetHoldOff())
+}
+
 func (d *Display) depositSweepTick() bool {
 	samples := d.currentRecord.Samples
 	total := len(samples)
@@ -227,7 +669,6 @@ func (d *Display) depositSweepTick() bool {
 	}
 
 	screenW := float64(d.layoutWidth)
-	screenH := float64(d.layoutHeight)
 
 	ticksPerSweep := d.sweepDuration * float64(ebiten.TPS())
 	pxPerTick := screenW / ticksPerSweep
@@ -237,41 +678,135 @@ func (d *Display) depositSweepTick() bool {
 		curX = screenW
 	}
 
-	toSampleIdx := func(px float64) int {
-		idx := int((px / screenW) * float64(total-1))
-		if idx < 0 {
-			return 0
-		}
-		if idx >= total {
-			return total - 1
-		}
-		return idx
+	depositOp := &ebiten.DrawImageOptions{}
+	depositOp.Blend = ebiten.BlendLighter
+
+	d.depositMathSweep(d.sweepPixelX, curX, depositOp)
+
+	if d.currentRecord.Max != nil {
+		d.depositPeakColumns(d.sweepPixelX, curX)
+		d.sweepPixelX = curX
+		return curX < screenW
 	}
 
-	dx := curX - d.prevPixelX
-	dy := sampleToScreenY(float64(samples[toSampleIdx(curX)]), screenH) - d.prevPixelY
+	d.prevPixelY = d.depositTrace(samples, d.beamSprite, d.prevPixelX, d.prevPixelY, curX, depositOp)
+	d.prevPixelX = curX
+	d.sweepPixelX = curX
+
+	return curX < screenW
+}
+
+// depositTrace moves the beam along samples from (fromX, fromY) to the
+// sample drawn at toX, stepping finely enough that steep edges stay
+// continuous, and returns the y it ends at.
+func (d *Display) depositTrace(samples []float32, sprite *ebiten.Image, fromX, fromY, toX float64, op *ebiten.DrawImageOptions) float64 {
+	screenW := float64(d.layoutWidth)
+	total := len(samples)
+
+	toY := d.sampleToScreenY(float64(samples[toSampleIdx(toX, screenW, total)]))
+	dx := toX - fromX
+	dy := toY - fromY
 	dist := math.Sqrt(dx*dx + dy*dy)
 	steps := int(dist/subsampleStep) + 1
 
+	for s := 0; s <= steps; s++ {
+		t := float64(s) / float64(steps)
+		px := fromX + dx*t
+		py := d.sampleToScreenY(float64(samples[toSampleIdx(px, screenW, total)]))
+		depositBeam(d.phosphorA, sprite, px, py, op)
+	}
+
+	return toY
+}
+
+// depositPeakColumns draws the full min/max extent of every pixel column in
+// [from, to), stretched to touch the previous column so the trace stays
+// connected.
+func (d *Display) depositPeakColumns(from, to float64) {
+	lo, hi := d.currentRecord.Min, d.currentRecord.Max
+	buckets := len(hi)
+
+	screenW := float64(d.layoutWidth)
+
 	depositOp := &ebiten.DrawImageOptions{}
 	depositOp.Blend = ebiten.BlendLighter
 
-	for s := 0; s <= steps; s++ {
-		t := float64(s) / float64(steps)
-		px := d.prevPixelX + dx*t
-		py := sampleToScreenY(float64(samples[toSampleIdx(px)]), screenH)
-		depositBeam(d.phosphorA, d.beamSprite, px, py, depositOp)
+	for x := math.Ceil(from); x < to; x++ {
+		b := min(int(x/screenW*float64(buckets)), buckets-1)
+
+		top := d.sampleToScreenY(float64(hi[b]))
+		bottom := d.sampleToScreenY(float64(lo[b]))
+
+		d.depositColumn(x, top, bottom, d.prevPeakTop, d.prevPeakBottom, depositOp)
+		d.prevPeakTop, d.prevPeakBottom = top, bottom
 	}
+}
 
-	d.prevPixelX = curX
-	d.prevPixelY = sampleToScreenY(float64(samples[toSampleIdx(curX)]), screenH)
-	d.sweepPixelX = curX
+// depositColumn draws a vertical beam span at x from top to bottom, stretched
+// to touch the previous column's span so the trace stays connected.
+func (d *Display) depositColumn(x, top, bottom, prevTop, prevBottom float64, op *ebiten.DrawImageOptions) {
+	d.depositColumnOn(d.phosphorA, d.beamSprite, x, top, bottom, prevTop, prevBottom, op)
+}
 
-	return curX < screenW
+func (d *Display) depositColumnOn(dst, sprite *ebiten.Image, x, top, bottom, prevTop, prevBottom float64, op *ebiten.DrawImageOptions) {
+	if bottom < prevTop && !math.IsInf(prevTop, 0) {
+		bottom = prevTop
+	}
+	if top > prevBottom && !math.IsInf(prevBottom, 0) {
+		top = prevBottom
+	}
+
+	for y := top; y <= bottom; y += peakStep {
+		depositBeam(dst, sprite, x, y, op)
+	}
 }
 
-func sampleToScreenY(sample, screenH float64) float64 {
-	return (screenH / 2) - (sample * screenH / 2)
+// drawEnvelope fills the band between the envelope extremes of the latest
+// record, one column per pixel, so the live trace is drawn on top of it.
+func (d *Display) drawEnvelope(dst *ebiten.Image) {
+	if d.acquirer.GetMode() != acquisition.ModeEnvelope {
+		return
+	}
+
+	total := len(d.envelopeMax)
+	if total == 0 {
+		return
+	}
+
+	screenW := float64(d.layoutWidth)
+
+	for x := range d.layoutWidth {
+		idx := min(int(float64(x)/screenW*float64(total-1)), total-1)
+		top := d.sampleToScreenY(float64(d.envelopeMax[idx]))
+		bottom := d.sampleToScreenY(float64(d.envelopeMin[idx]))
+		vector.FillRect(dst, float32(x), float32(top), 1, float32(max(bottom-top, 1)), d.envelopeColor, false)
+	}
+}
+
+// sampleToScreenY maps a sample through the acquirer's vertical offset and
+// gain; at a gain of one, full scale fills the screen height.
+func (d *Display) sampleToScreenY(sample float64) float64 {
+	screenH := float64(d.layoutHeight)
+	v := (sample - d.acquirer.GetOffset()) * d.acquirer.GetGain()
+	return (screenH / 2) - (v * screenH / 2)
+}
+
+// screenToSample is the inverse of sampleToScreenY.
+func (d *Display) screenToSample(y float64) float64 {
+	v := 1 - 2*y/float64(d.layoutHeight)
+	return v/d.acquirer.GetGain() + d.acquirer.GetOffset()
+}
+
+// toSampleIdx maps a pixel column to the record sample drawn there.
+func toSampleIdx(px, screenW float64, total int) int {
+	idx := int((px / screenW) * float64(total-1))
+	if idx < 0 {
+		return 0
+	}
+	if idx >= total {
+		return total - 1
+	}
+	return idx
 }
 
 func depositBeam(dst, sprite *ebiten.Image, x, y float64, op *ebiten.DrawImageOptions) {
@@ -307,21 +842,29 @@ func makeBeamSprite(radius int, p Phosphor) *ebiten.Image {
 	return img
 }
 
+func scaleColor(c color.RGBA, k float64) color.RGBA {
+	return color.RGBA{
+		R: uint8(float64(c.R) * k),
+		G: uint8(float64(c.G) * k),
+		B: uint8(float64(c.B) * k),
+		A: uint8(float64(c.A) * k),
+	}
+}
+
 func drawGrid(screen *ebiten.Image, w, h int) {
-	const cols, rows = 10, 8
 	gridCol := color.RGBA{R: 0, G: 0, B: 0, A: 100}
 
-	cellW := float64(w) / cols
-	cellH := float64(h) / rows
+	cellW := float64(w) / gridCols
+	cellH := float64(h) / gridRows
 
 	// Vertical lines.
-	for i := 1; i < cols; i++ {
+	for i := 1; i < gridCols; i++ {
 		x := float32(float64(i) * cellW)
 		vector.StrokeLine(screen, x, 0, x, float32(h), 3, gridCol, true)
 	}
 
 	// Horizontal lines.
-	for i := 1; i < rows; i++ {
+	for i := 1; i < gridRows; i++ {
 		y := float32(float64(i) * cellH)
 		vector.StrokeLine(screen, 0, y, float32(w), y, 3, gridCol, true)
 	}
@@ -0,0 +1,92 @@
+package display
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	"oscilloscope/internal/filter"
+)
+
+const (
+	// cutoffStep is the ratio one key press moves the cutoff by, a third
+	// of an octave.
+	cutoffStep = 1.2599210498948732
+	minCutoff  = 10.0
+	gainStepDB = 1.5
+)
+
+// handleFilterKeys maps V to cycling the filter kind, Z and X to lowering
+// and raising its frequency, Q to cycling the resonance, 9 and 0 to the
+// shelving gain and B to the DC blocker.
+func (d *Display) handleFilterKeys() {
+	if d.filter == nil {
+		return
+	}
+
+	s := d.filter.Get()
+	prev := s
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyV) {
+		s.Kind = s.Kind.Next()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyB) {
+		s.DCBlock = !s.DCBlock
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyZ) {
+		s.Cutoff = math.Max(s.Cutoff/cutoffStep, minCutoff)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyX) {
+		s.Cutoff = math.Min(s.Cutoff*cutoffStep, float64(d.filter.SampleRate)/2)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyQ) {
+		s.Q = nextQ(s.Q)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.Key9) {
+		s.GainDB -= gainStepDB
+	}
+	if inpututil.IsKeyJustPressed(ebiten.Key0) {
+		s.GainDB += gainStepDB
+	}
+
+	if s != prev {
+		d.filter.Set(s)
+		d.stats.Reset()
+	}
+}
+
+// nextQ returns the first step above q, wrapping to the lowest.
+func nextQ(q float64) float64 {
+	for _, step := range filter.QSteps {
+		if step > q+1e-9 {
+			return step
+		}
+	}
+	return filter.QSteps[0]
+}
+
+func (d *Display) filterLabel() string {
+	if d.filter == nil {
+		return ""
+	}
+
+	s := d.filter.Get()
+	label := ""
+	switch s.Kind {
+	case filter.Off:
+	case filter.LowShelf, filter.HighShelf:
+		label = fmt.Sprintf("FILTER %s %s %+.1f dB", s.Kind, formatSI(s.Cutoff, "Hz"), s.GainDB)
+	default:
+		label = fmt.Sprintf("FILTER %s %s Q %.2f", s.Kind, formatSI(s.Cutoff, "Hz"), s.Q)
+	}
+
+	if s.DCBlock {
+		if label == "" {
+			label = "FILTER"
+		}
+		label += " +DC block"
+	}
+	return label
+}
@@ -0,0 +1,144 @@
+package display
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"oscilloscope/internal/acquisition"
+	"oscilloscope/internal/mathchan"
+	"oscilloscope/internal/record"
+)
+
+// mathColors are cycled through for successive math channels.
+var mathColors = []color.RGBA{
+	{R: 255, G: 90, B: 200, A: 255},
+	{R: 255, G: 170, B: 40, A: 255},
+	{R: 140, G: 140, B: 255, A: 255},
+}
+
+// mathTrace is one math channel. It is drawn by the same beam as the input
+// channel, in its own colour, in every mode that shows a trace.
+type mathTrace struct {
+	expr   *mathchan.Expr
+	sprite *ebiten.Image
+	err    error
+
+	// Sweep modes
+	rec   record.Record
+	prevY float64
+
+	// Roll mode
+	stream  *mathchan.Stream
+	roll    rollColumns
+	history [2][]float32 // min, max of the scrolled-back window
+}
+
+func newMathTraces(exprs []*mathchan.Expr) []*mathTrace {
+	traces := make([]*mathTrace, len(exprs))
+	for i, e := range exprs {
+		beam := Phosphor{BeamColor: mathColors[i%len(mathColors)]}
+		traces[i] = &mathTrace{expr: e, sprite: makeBeamSprite(beamSpriteRadius, beam)}
+	}
+	return traces
+}
+
+// updateMath evaluates every math channel over rec at the start of a
+// sweep. A channel that cannot be evaluated draws nothing and reports why
+// in the overlay.
+func (d *Display) updateMath(rec record.Record) {
+	inputs := mathchan.Inputs(rec)
+
+	for _, t := range d.math {
+		t.rec, t.err = t.expr.Eval(inputs)
+		if t.err == nil && len(t.rec.Samples) > 0 {
+			t.prevY = d.sampleToScreenY(float64(t.rec.Samples[0]))
+		}
+	}
+}
+
+// depositMathSweep moves every math channel's beam from fromX to toX,
+// alongside the input channel's.
+func (d *Display) depositMathSweep(fromX, toX float64, op *ebiten.DrawImageOptions) {
+	for _, t := range d.math {
+		if t.err == nil && len(t.rec.Samples) > 0 {
+			t.prevY = d.depositTrace(t.rec.Samples, t.sprite, fromX, t.prevY, toX, op)
+		}
+	}
+}
+
+// resetMathRoll restarts every math channel's roll columns and stream.
+func (d *Display) resetMathRoll() {
+	for _, t := range d.math {
+		t.stream = t.expr.NewStream()
+		t.roll.reset()
+		t.err = nil
+	}
+}
+
+// rollMath evaluates the next block of ch1 and its siblings for every math
+// channel. A channel that fails stops rolling until the roll restarts.
+func (d *Display) rollMath(samples []float32, siblings [][]float32) [][]float32 {
+	inputs := map[int][]float32{0: samples}
+	for i, s := range siblings {
+		inputs[i+1] = s
+	}
+
+	outs := make([][]float32, len(d.math))
+	for i, t := range d.math {
+		if t.err != nil {
+			continue
+		}
+		outs[i], t.err = t.stream.Process(inputs, d.acquirer.Stream.SampleRate)
+		if t.err != nil {
+			t.roll.reset()
+		}
+	}
+	return outs
+}
+
+// historyMath decimates the math channels over a scrolled-back window of
+// the input. The deep history only keeps ch1, so a channel that reads
+// another input reports that its record is missing.
+func (d *Display) historyMath(samples []float32, columns int) {
+	rec := record.Record{Samples: samples, SampleRate: d.acquirer.Stream.SampleRate}
+	for _, t := range d.math {
+		t.history = [2][]float32{}
+
+		out, err := t.expr.Eval(map[int]record.Record{0: rec})
+		if err != nil {
+			t.err = err
+			continue
+		}
+		t.history[0], t.history[1] = acquisition.PeakDetect(out.Samples, columns)
+	}
+}
+
+// depositColumns draws min/max columns from x = offset, step pixels
+// apart, each stretched to meet the one before.
+func (d *Display) depositColumns(dst, sprite *ebiten.Image, lo, hi []float32, offset, step float64, op *ebiten.DrawImageOptions) {
+	prevTop, prevBottom := math.Inf(1), math.Inf(-1)
+	for i := range hi {
+		top := d.sampleToScreenY(float64(hi[i]))
+		bottom := d.sampleToScreenY(float64(lo[i]))
+		d.depositColumnOn(dst, sprite, offset+float64(i)*step, top, bottom, prevTop, prevBottom, op)
+		prevTop, prevBottom = top, bottom
+	}
+}
+
+func (d *Display) mathLabel() string {
+	var b strings.Builder
+	for i, t := range d.math {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		fmt.Fprintf(&b, "M%d = %s", i+1, t.expr)
+		if t.err != nil {
+			fmt.Fprintf(&b, "  (%v)", t.err)
+		}
+	}
+	return b.String()
+}
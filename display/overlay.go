@@ -0,0 +1,180 @@
+package display
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+
+	"oscilloscope/internal/acquisition"
+	"oscilloscope/internal/measure"
+)
+
+// Size of a glyph in the debug font ebitenutil draws with.
+const (
+	glyphWidth  = 6
+	glyphHeight = 16
+)
+
+// overlayMargin is the distance in pixels between the text and the edge of
+// the screen.
+const overlayMargin = 12
+
+type Placement int
+
+const (
+	TopLeft Placement = iota
+	TopRight
+	BottomLeft
+	BottomRight
+	placementCount
+)
+
+func (p Placement) Next() Placement {
+	return (p + 1) % placementCount
+}
+
+type OverlayConfig struct {
+	Placement Placement
+	// Scale enlarges the font, in whole multiples for crisp glyphs.
+	Scale float64
+	// Curved draws the text before the CRT pass so it bends with the
+	// screen. Flat text stays easier to read.
+	Curved bool
+	// Measurements lists what is shown below the settings.
+	Measurements []measure.Kind
+}
+
+func DefaultOverlayConfig() OverlayConfig {
+	return OverlayConfig{
+		Placement: TopLeft,
+		Scale:     2,
+		Measurements: []measure.Kind{
+			measure.Frequency,
+			measure.PeakToPeak,
+			measure.RMS,
+			measure.DutyCycle,
+		},
+	}
+}
+
+// drawOverlay renders the readout at its native size and stretches it onto
+// dst at the configured corner.
+func (d *Display) drawOverlay(dst *ebiten.Image) {
+	text := d.statusText()
+	lines := strings.Split(text, "\n")
+
+	w := 0
+	for _, l := range lines {
+		w = max(w, len(l)*glyphWidth)
+	}
+	h := len(lines) * glyphHeight
+
+	w = min(w, d.layoutWidth)
+	h = min(h, d.layoutHeight)
+	if w == 0 || h == 0 {
+		return
+	}
+
+	d.overlayCanvas.Clear()
+	ebitenutil.DebugPrint(d.overlayCanvas, text)
+
+	scale := max(d.overlay.Scale, 1)
+	x, y := overlayMargin, overlayMargin
+	switch d.overlay.Placement {
+	case TopRight:
+		x = d.layoutWidth - overlayMargin - int(float64(w)*scale)
+	case BottomLeft:
+		y = d.layoutHeight - overlayMargin - int(float64(h)*scale)
+	case BottomRight:
+		x = d.layoutWidth - overlayMargin - int(float64(w)*scale)
+		y = d.layoutHeight - overlayMargin - int(float64(h)*scale)
+	}
+
+	op := &ebiten.DrawImageOptions{Filter: ebiten.FilterNearest}
+	op.GeoM.Scale(scale, scale)
+	op.GeoM.Translate(float64(x), float64(y))
+	dst.DrawImage(d.overlayCanvas.SubImage(image.Rect(0, 0, w, h)).(*ebiten.Image), op)
+}
+
+// settingsLabel describes the stream, the scale of the grid and the trigger.
+func (d *Display) settingsLabel() string {
+	stream := d.acquirer.Stream
+	timeDiv := d.acquirer.GetRecordLength().Seconds() / gridCols
+	if d.acquirer.GetMode() == acquisition.ModeRoll {
+		timeDiv = d.rollDuration.Seconds() / gridCols
+	}
+
+	// At a gain of one, full scale spans the height of the grid
+	ampDiv := 2.0 / gridRows / d.acquirer.GetGain()
+
+	// The trigger always fires on a rising crossing of Level; polarity only
+	// mirrors the hysteresis band, so show the band it actually uses
+	t := d.acquirer.GetTrigger()
+	lower, upper := t.Band()
+
+	return fmt.Sprintf("%s  %s/div  %.3g FS/div  OFFSET %+.3f\nTRIG RISE %+.3f HYST %+.3f/%+.3f",
+		formatSI(float64(stream.SampleRate), "Hz"), formatSI(timeDiv, "s"), ampDiv, d.acquirer.GetOffset(),
+		t.Level, lower, upper)
+}
+
+// measurementsLabel shows the configured measurements of the acquired
+// channel with their running statistics.
+func (d *Display) measurementsLabel() string {
+	var b strings.Builder
+	for i, k := range d.overlay.Measurements {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+
+		st, ok := d.stats.Get(measure.Key{Kind: k, Channel: d.acquirer.Channel})
+		if !ok {
+			fmt.Fprintf(&b, "%-6s ---", k)
+			continue
+		}
+		fmt.Fprintf(&b, "%-6s %s  mean %s  sd %s  n %d", k,
+			formatMeasurement(k, st.Current), formatMeasurement(k, st.Mean),
+			formatMeasurement(k, st.StdDev()), st.Count)
+	}
+	return b.String()
+}
+
+func formatMeasurement(k measure.Kind, v float64) string {
+	switch k {
+	case measure.Frequency:
+		return formatSI(v, "Hz")
+	case measure.Period, measure.RiseTime, measure.FallTime,
+		measure.PositiveWidth, measure.NegativeWidth:
+		return formatSI(v, "s")
+	case measure.Overshoot, measure.DutyCycle:
+		return fmt.Sprintf("%.1f%%", v*100)
+	case measure.CrestFactor:
+		return fmt.Sprintf("%.3g", v)
+	default:
+		return fmt.Sprintf("%.3g FS", v)
+	}
+}
+
+// formatSI prints v with three significant digits and an SI prefix.
+func formatSI(v float64, unit string) string {
+	if v == 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+		return fmt.Sprintf("%g %s", v, unit)
+	}
+
+	prefixes := []struct {
+		scale  float64
+		prefix string
+	}{
+		{1e9, "G"}, {1e6, "M"}, {1e3, "k"}, {1, ""}, {1e-3, "m"}, {1e-6, "u"}, {1e-9, "n"},
+	}
+	for _, p := range prefixes {
+		if math.Abs(v) >= p.scale {
+			return fmt.Sprintf("%.3g %s%s", v/p.scale, p.prefix, unit)
+		}
+	}
+	last := prefixes[len(prefixes)-1]
+	return fmt.Sprintf("%.3g %s%s", v/last.scale, last.prefix, unit)
+}
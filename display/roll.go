@@ -0,0 +1,232 @@
+package display
+
+import (
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"oscilloscope/internal/acquisition"
+)
+
+const minRollDuration = 100 * time.Millisecond
+
+type historyView struct {
+	anchor   int
+	offset   time.Duration
+	duration time.Duration
+}
+
+// rollColumns decimates a stream into min/max columns, one per pixel.
+type rollColumns struct {
+	min, max       []float32
+	accMin, accMax float32
+}
+
+func (c *rollColumns) reset() {
+	c.min, c.max = c.min[:0], c.max[:0]
+}
+
+// add folds v into the column being built; first starts a new one.
+func (c *rollColumns) add(v float32, first bool) {
+	if first {
+		c.accMin, c.accMax = v, v
+		return
+	}
+	c.accMin = min(c.accMin, v)
+	c.accMax = max(c.accMax, v)
+}
+
+func (c *rollColumns) flush() {
+	c.min = append(c.min, c.accMin)
+	c.max = append(c.max, c.accMax)
+}
+
+// trim keeps the newest width columns.
+func (c *rollColumns) trim(width int) {
+	if n := len(c.max); n > width {
+		c.min = append(c.min[:0], c.min[n-width:]...)
+		c.max = append(c.max[:0], c.max[n-width:]...)
+	}
+}
+
+// resetRoll drops the roll history and refills it from whatever the ring
+// still holds for the current roll duration.
+func (d *Display) resetRoll() {
+	d.rolling = true
+	d.roll.reset()
+	d.rollCount = 0
+	d.resetMathRoll()
+
+	window := d.acquirer.Stream.Samples(d.rollDuration)
+	if bounds, ok := d.ring.Snapshot(); ok {
+		d.rollNext = max(bounds.Newest-window, bounds.Oldest)
+	}
+}
+
+// updateRoll consumes every sample written since the last tick, decimates
+// it into min/max columns and redraws the trace with the newest column at
+// the right edge.
+func (d *Display) updateRoll() {
+	if !d.rolling {
+		d.resetRoll()
+	}
+
+	d.consumeRoll()
+
+	lo, hi := d.roll.min, d.roll.max
+	if d.rollOffset > 0 {
+		lo, hi = d.historyColumns()
+	}
+
+	d.phosphorA.Clear()
+
+	depositOp := &ebiten.DrawImageOptions{}
+	depositOp.Blend = ebiten.BlendLighter
+
+	d.depositColumns(d.phosphorA, d.beamSprite, lo, hi, float64(d.layoutWidth-len(hi)), 1, depositOp)
+
+	for _, t := range d.math {
+		lo, hi := t.roll.min, t.roll.max
+		if d.rollOffset > 0 {
+			lo, hi = t.history[0], t.history[1]
+		}
+		d.depositColumns(d.phosphorA, t.sprite, lo, hi, float64(d.layoutWidth-len(hi)), 1, depositOp)
+	}
+}
+
+func (d *Display) consumeRoll() {
+	bounds, ok := d.ring.Snapshot()
+	if !ok {
+		return
+	}
+
+	start := max(d.rollNext, bounds.Oldest)
+	end := bounds.Newest
+	if end <= start {
+		return
+	}
+
+	// Copy rather than view: start may be the oldest sample, which the
+	// producer can overwrite while the columns are being built. A failed
+	// read leaves rollNext alone so the next tick retries.
+	if cap(d.rollBuf) < end-start {
+		d.rollBuf = make([]float32, end-start)
+	}
+	samples := d.rollBuf[:end-start]
+	if err := d.ring.ReadInto(samples, start); err != nil {
+		return
+	}
+	siblings, ok := d.readSiblings(start, end)
+	if !ok {
+		return
+	}
+	d.rollNext = end
+
+	perColumn := max(d.acquirer.Stream.Samples(d.rollDuration)/d.layoutWidth, 1)
+	maths := d.rollMath(samples, siblings)
+
+	for i, v := range samples {
+		first := d.rollCount == 0
+		d.roll.add(v, first)
+		for j, out := range maths {
+			if out != nil {
+				d.math[j].roll.add(out[i], first)
+			}
+		}
+		d.rollCount++
+
+		if d.rollCount < perColumn {
+			continue
+		}
+
+		d.roll.flush()
+		for j, out := range maths {
+			if out != nil {
+				d.math[j].roll.flush()
+			}
+		}
+		d.rollCount = 0
+	}
+
+	d.roll.trim(d.layoutWidth)
+	for _, t := range d.math {
+		t.roll.trim(d.layoutWidth)
+	}
+}
+
+// readSiblings copies [start, end) of every other channel for the math
+// channels. The producer writes ch1 last, so they hold whatever ch1 does.
+func (d *Display) readSiblings(start, end int) ([][]float32, bool) {
+	if len(d.math) == 0 {
+		return nil, true
+	}
+
+	if len(d.siblingBufs) != len(d.siblings) {
+		d.siblingBufs = make([][]float32, len(d.siblings))
+	}
+	for i, ring := range d.siblings {
+		if cap(d.siblingBufs[i]) < end-start {
+			d.siblingBufs[i] = make([]float32, end-start)
+		}
+		d.siblingBufs[i] = d.siblingBufs[i][:end-start]
+		if err := ring.ReadInto(d.siblingBufs[i], start); err != nil {
+			return nil, false
+		}
+	}
+	return d.siblingBufs, true
+}
+
+func (d *Display) scaleRollDuration(k float64) {
+	d.rollDuration = max(time.Duration(float64(d.rollDuration)*k), minRollDuration)
+	d.rolling = false
+}
+
+// scrollRoll moves the roll window back (positive steps) or forward through
+// the deep history in half-screen steps. Scrolling back freezes the view at
+// the newest sample at that moment; scrolling to zero returns to live.
+func (d *Display) scrollRoll(steps int) {
+	if d.rollOffset == 0 && steps > 0 {
+		if bounds, ok := d.ring.Snapshot(); ok {
+			d.rollAnchor = bounds.Newest
+		}
+	}
+
+	d.rollOffset = max(d.rollOffset+time.Duration(steps)*d.rollDuration/2, 0)
+}
+
+// historyColumns decimates the scrolled-back window, read from the deep
+// history, into min/max columns. The result is cached until the view moves.
+func (d *Display) historyColumns() ([]float32, []float32) {
+	view := historyView{anchor: d.rollAnchor, offset: d.rollOffset, duration: d.rollDuration}
+	if view == d.historyView {
+		return d.historyMin, d.historyMax
+	}
+	d.historyView = view
+	d.historyMin, d.historyMax = nil, nil
+	for _, t := range d.math {
+		t.history = [2][]float32{}
+	}
+
+	window := d.acquirer.Stream.Samples(d.rollDuration)
+	perColumn := max(window/d.layoutWidth, 1)
+
+	bounds, ok := d.deep.Snapshot()
+	if !ok {
+		return nil, nil
+	}
+
+	end := d.rollAnchor - d.acquirer.Stream.Samples(d.rollOffset)
+	start := max(end-window, bounds.Oldest)
+	if end <= start {
+		return nil, nil
+	}
+
+	samples, err := d.deep.ReadRange(start, end)
+	if err != nil {
+		return nil, nil
+	}
+
+	d.historyMin, d.historyMax = acquisition.PeakDetect(samples, len(samples)/perColumn)
+	d.historyMath(samples, len(samples)/perColumn)
+	return d.historyMin, d.historyMax
+}
@@ -0,0 +1,81 @@
+package display
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"oscilloscope/internal/acquisition"
+	"oscilloscope/internal/mathchan"
+)
+
+type segmentView struct {
+	version  uint64
+	selected int
+	overlay  bool
+}
+
+// updateSegments shows the selected segment, or every segment when overlay
+// is on. The trace is only re-rendered when the store or the view changes.
+func (d *Display) updateSegments() {
+	view := segmentView{
+		version:  d.segments.Version(),
+		selected: d.segmentIndex,
+		overlay:  d.segmentOverlay,
+	}
+
+	if view != d.segmentView {
+		d.segmentView = view
+		d.renderSegments()
+	}
+
+	d.phosphorA.Clear()
+	d.phosphorA.DrawImage(d.segmentCanvas, nil)
+}
+
+func (d *Display) renderSegments() {
+	d.segmentCanvas.Clear()
+
+	segs := d.segments.All()
+	if len(segs) == 0 {
+		return
+	}
+
+	if !d.segmentOverlay {
+		d.segmentIndex = min(d.segmentIndex, len(segs)-1)
+		segs = segs[d.segmentIndex : d.segmentIndex+1]
+	}
+
+	depositOp := &ebiten.DrawImageOptions{}
+	depositOp.Blend = ebiten.BlendLighter
+
+	for _, seg := range segs {
+		d.depositSegment(d.beamSprite, seg.Record.Samples, depositOp)
+
+		for _, t := range d.math {
+			out, err := t.expr.Eval(mathchan.Inputs(seg.Record))
+			t.err = err
+			if err == nil {
+				d.depositSegment(t.sprite, out.Samples, depositOp)
+			}
+		}
+	}
+}
+
+// depositSegment draws one record across the full width of the segment
+// canvas.
+func (d *Display) depositSegment(sprite *ebiten.Image, samples []float32, op *ebiten.DrawImageOptions) {
+	lo, hi := acquisition.PeakDetect(samples, d.layoutWidth)
+	if len(hi) == 0 {
+		return
+	}
+	step := float64(d.layoutWidth) / float64(len(hi))
+	d.depositColumns(d.segmentCanvas, sprite, lo, hi, 0, step, op)
+}
+
+func (d *Display) stepSegment(delta int) {
+	n := d.segments.Len()
+	if n == 0 {
+		d.segmentIndex = 0
+		return
+	}
+	d.segmentIndex = (d.segmentIndex + delta + n) % n
+}
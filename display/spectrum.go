@@ -0,0 +1,144 @@
+package display
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"oscilloscope/internal/spectrum"
+)
+
+const (
+	// spectrumFloor is the level in dBFS at the bottom of the screen; 0 dBFS
+	// is at the top.
+	spectrumFloor = -120.0
+	// spectrumMinFreq is the left edge of the logarithmic axis.
+	spectrumMinFreq = 20.0
+	spectrumPeaks   = 3
+	markerSize      = 6
+)
+
+var heldColor = color.RGBA{R: 255, G: 120, B: 60, A: 160}
+
+// updateSpectrum analyses the newest record, if there is one.
+func (d *Display) updateSpectrum() {
+	select {
+	case rec, ok := <-d.recordCh:
+		if !ok {
+			return
+		}
+		d.currentRecord = &rec
+		d.spectrumFrame = d.analyzer.Record(rec)
+		d.spectrumPeaks = spectrum.Peaks(d.spectrumFrame, spectrumPeaks)
+	default:
+	}
+}
+
+func (d *Display) handleSpectrumKeys() {
+	a := d.analyzer
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyBracketRight) {
+		a.Size = min(a.Size*2, spectrum.MaxSize)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyBracketLeft) {
+		a.Size = max(a.Size/2, spectrum.MinSize)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEqual) {
+		a.Averages *= 2
+		a.Reset()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyMinus) {
+		a.Averages = max(a.Averages/2, 1)
+		a.Reset()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyW) {
+		a.Window = a.Window.Next()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyU) {
+		a.PeakHold = !a.PeakHold
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyL) {
+		d.spectrumLog = !d.spectrumLog
+	}
+}
+
+// freqToScreenX maps a frequency onto the horizontal axis, linear from 0 Hz
+// or logarithmic from spectrumMinFreq, up to the Nyquist frequency.
+func (d *Display) freqToScreenX(freq, nyquist float64) float64 {
+	w := float64(d.layoutWidth)
+	if !d.spectrumLog {
+		return freq / nyquist * w
+	}
+	if freq <= spectrumMinFreq {
+		return 0
+	}
+	return math.Log(freq/spectrumMinFreq) / math.Log(nyquist/spectrumMinFreq) * w
+}
+
+func levelToScreenY(level, screenH float64) float64 {
+	return math.Min(level/spectrumFloor, 1) * screenH
+}
+
+func (d *Display) drawSpectrum(dst *ebiten.Image) {
+	f := d.spectrumFrame
+	if len(f.Levels) < 2 {
+		return
+	}
+
+	nyquist := f.Frequency(float64(len(f.Levels) - 1))
+	if f.Held != nil {
+		d.drawLevels(dst, f.Held, f.BinWidth, nyquist, heldColor)
+	}
+	d.drawLevels(dst, f.Levels, f.BinWidth, nyquist, d.phosphor.BeamColor)
+
+	h := float64(d.layoutHeight)
+	for _, p := range d.spectrumPeaks {
+		x := float32(d.freqToScreenX(p.Frequency, nyquist))
+		y := float32(levelToScreenY(p.Level, h))
+		vector.StrokeLine(dst, x-markerSize, y-2*markerSize, x, y-markerSize, 2, timeCursorColor, true)
+		vector.StrokeLine(dst, x+markerSize, y-2*markerSize, x, y-markerSize, 2, timeCursorColor, true)
+	}
+}
+
+func (d *Display) drawLevels(dst *ebiten.Image, levels []float64, binWidth, nyquist float64, clr color.RGBA) {
+	h := float64(d.layoutHeight)
+
+	prevX := float32(d.freqToScreenX(binWidth, nyquist))
+	prevY := float32(levelToScreenY(levels[1], h))
+	for k := 2; k < len(levels); k++ {
+		x := float32(d.freqToScreenX(float64(k)*binWidth, nyquist))
+		y := float32(levelToScreenY(levels[k], h))
+		vector.StrokeLine(dst, prevX, prevY, x, y, 2, clr, true)
+		prevX, prevY = x, y
+	}
+}
+
+func (d *Display) spectrumLabel() string {
+	a := d.analyzer
+	axis := "lin"
+	if d.spectrumLog {
+		axis = "log"
+	}
+
+	label := fmt.Sprintf("%d %s avg x%d %s", a.Size, a.Window, a.Averages, axis)
+	if a.PeakHold {
+		label += " hold"
+	}
+	return label
+}
+
+func (d *Display) peaksLabel() string {
+	var b strings.Builder
+	for i, p := range d.spectrumPeaks {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		fmt.Fprintf(&b, "PEAK %d %s %.1f dBFS", i+1, formatSI(p.Frequency, "Hz"), p.Level)
+	}
+	return b.String()
+}
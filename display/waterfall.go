@@ -0,0 +1,159 @@
+package display
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	"oscilloscope/internal/spectrum"
+)
+
+const (
+	// waterfallRowHeight is how many pixels each FFT frame occupies.
+	waterfallRowHeight = 2
+	// waterfallMaxRows bounds the frames analysed per tick, so a slow
+	// frame skips ahead instead of falling further behind.
+	waterfallMaxRows = 16
+	// waterfallGlow is the per-tick decay of the afterglow style.
+	waterfallGlow = 0.01
+	rangeStep     = 10.0
+	minRange      = 20.0
+)
+
+// resetWaterfall restarts the analysis at the newest samples in the ring.
+func (d *Display) resetWaterfall() {
+	d.waterfallRunning = true
+	d.waterfallA.Clear()
+	d.waterfallNext = -1
+}
+
+// updateWaterfall analyses every frame that became available since the
+// last tick, half overlapping the previous one, and scrolls each in as a
+// new row at the top.
+func (d *Display) updateWaterfall() {
+	if !d.waterfallRunning {
+		d.resetWaterfall()
+	}
+
+	a := d.waterfall
+	a.Size, a.Window = d.analyzer.Size, d.analyzer.Window
+
+	bounds, ok := d.ring.Snapshot()
+	if !ok {
+		return
+	}
+
+	size := a.Size
+	hop := max(size/2, 1)
+	if d.waterfallNext < bounds.Oldest || bounds.Newest-(d.waterfallNext+size) > waterfallMaxRows*hop {
+		d.waterfallNext = max(bounds.Newest-size, bounds.Oldest)
+	}
+	if cap(d.waterfallBuf) < size {
+		d.waterfallBuf = make([]float32, size)
+	}
+	buf := d.waterfallBuf[:size]
+
+	var rows [][]float64
+	for d.waterfallNext+size <= bounds.Newest && len(rows) < waterfallMaxRows {
+		if err := d.ring.ReadInto(buf, d.waterfallNext); err != nil {
+			d.waterfallNext = -1
+			return
+		}
+		frame := a.Process(buf, d.acquirer.Stream.SampleRate)
+		rows = append(rows, d.waterfallRow(frame))
+		d.waterfallNext += hop
+	}
+
+	d.scrollWaterfall(rows)
+}
+
+// scrollWaterfall moves the image down by one row per frame and paints the
+// new rows at the top, newest first.
+func (d *Display) scrollWaterfall(rows [][]float64) {
+	d.waterfallB.Clear()
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(0, float64(len(rows)*waterfallRowHeight))
+	if d.afterglow {
+		d.waterfallB.DrawRectShader(d.layoutWidth, d.layoutHeight, d.decayShader, &ebiten.DrawRectShaderOptions{
+			GeoM:     op.GeoM,
+			Images:   [4]*ebiten.Image{d.waterfallA},
+			Uniforms: map[string]any{"Decay": waterfallGlow, "Threshold": 0.0},
+		})
+	} else {
+		d.waterfallB.DrawImage(d.waterfallA, op)
+	}
+
+	for i, levels := range rows {
+		pix := d.waterfallPix[:0]
+		for _, l := range levels {
+			// The colour map spans waterfallRange dB up to 0 dBFS
+			c := d.colorMap.At((l + d.waterfallRange) / d.waterfallRange)
+			pix = append(pix, c.R, c.G, c.B, c.A)
+		}
+		d.waterfallPix = pix
+		d.waterfallLine.WritePixels(pix)
+
+		rowOp := &ebiten.DrawImageOptions{}
+		rowOp.GeoM.Scale(1, waterfallRowHeight)
+		rowOp.GeoM.Translate(0, float64((len(rows)-1-i)*waterfallRowHeight))
+		d.waterfallB.DrawImage(d.waterfallLine, rowOp)
+	}
+
+	d.waterfallA, d.waterfallB = d.waterfallB, d.waterfallA
+}
+
+// waterfallRow reduces a frame to one level per pixel column, keeping the
+// loudest bin that falls in each so narrow tones are not lost.
+func (d *Display) waterfallRow(f spectrum.Frame) []float64 {
+	row := make([]float64, d.layoutWidth)
+	nyquist := f.Frequency(float64(len(f.Levels) - 1))
+
+	for x := range row {
+		lo := d.screenXToFreq(float64(x), nyquist) / f.BinWidth
+		hi := d.screenXToFreq(float64(x+1), nyquist) / f.BinWidth
+
+		first := int(math.Ceil(lo))
+		last := min(int(math.Floor(hi)), len(f.Levels)-1)
+		if first > last {
+			row[x] = f.Levels[min(int(math.Round((lo+hi)/2)), len(f.Levels)-1)]
+			continue
+		}
+
+		level := spectrum.Floor
+		for k := first; k <= last; k++ {
+			level = math.Max(level, f.Levels[k])
+		}
+		row[x] = level
+	}
+	return row
+}
+
+// screenXToFreq is the inverse of freqToScreenX.
+func (d *Display) screenXToFreq(x, nyquist float64) float64 {
+	t := x / float64(d.layoutWidth)
+	if !d.spectrumLog {
+		return t * nyquist
+	}
+	return spectrumMinFreq * math.Pow(nyquist/spectrumMinFreq, t)
+}
+
+func (d *Display) handleWaterfallKeys() {
+	if inpututil.IsKeyJustPressed(ebiten.KeyG) {
+		d.colorMap = d.colorMap.Next()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyA) {
+		d.afterglow = !d.afterglow
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyPageUp) {
+		d.waterfallRange += rangeStep
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyPageDown) {
+		d.waterfallRange = max(d.waterfallRange-rangeStep, minRange)
+	}
+}
+
+func (d *Display) drawWaterfall(dst *ebiten.Image) {
+	dst.DrawImage(d.waterfallA, nil)
+}
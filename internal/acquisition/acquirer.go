@@ -3,16 +3,42 @@ package acquisition
 import (
 	"math"
 	"sync/atomic"
+	"time"
 
 	"oscilloscope/internal/memory"
 	"oscilloscope/internal/record"
+	"oscilloscope/internal/source"
 	"oscilloscope/internal/trigger"
 )
 
 type Acquirer struct {
 	Trigger          *trigger.Trigger
-	HoldOff          atomic.Int64
+	Stream           source.Stream
+	Channel          int
+	Mode             atomic.Int32
+	Buckets          atomic.Int64
+	AverageKind      atomic.Int32
+	AverageCount     atomic.Int64
+	HighResWidth     atomic.Int64
+	EnvelopeHold     atomic.Int64 // records, 0 holds forever
+	HoldOffMode      atomic.Int32
+	HoldOff          atomic.Int64 // time.Duration
+	HoldOffEvents    atomic.Int64
+	RecordLength     atomic.Int64  // time.Duration
+	Gain             atomic.Uint64 // float64 bits, screen heights per full scale
+	Offset           atomic.Uint64 // float64 bits, the level at screen centre
 	LastTriggerIndex int
+
+	// triggerSettings holds the latest trigger requested through
+	// SetTrigger. Build copies it into Trigger when it changes, so Trigger
+	// is only ever touched by the acquiring goroutine.
+	triggerSettings atomic.Pointer[trigger.Trigger]
+	appliedTrigger  *trigger.Trigger
+
+	// resume is where the next time-holdoff search may start without
+	// rescanning samples that are known not to trigger.
+	resume   int
+	sequence uint64
 }
 
 type Result struct {
@@ -20,16 +46,25 @@ type Result struct {
 	Ready  bool
 }
 
-func New(trig *trigger.Trigger) *Acquirer {
+func New(trig *trigger.Trigger, stream source.Stream) *Acquirer {
 	a := &Acquirer{
 		Trigger:          trig,
+		Stream:           stream,
 		LastTriggerIndex: -1,
 	}
-	a.HoldOff.Store(int64(math.Floor(DefaultHoldOff)))
+	a.HoldOff.Store(int64(DefaultHoldOff))
+	a.HoldOffEvents.Store(int64(DefaultHoldOffEvents))
+	a.AverageCount.Store(int64(DefaultAverageCount))
+	a.HighResWidth.Store(int64(DefaultHighResWidth))
+	a.EnvelopeHold.Store(int64(DefaultEnvelopeHold))
+	a.RecordLength.Store(int64(RecordDuration))
+	a.SetGain(DefaultGain)
+	a.SetTrigger(*trig)
+	a.appliedTrigger = a.triggerSettings.Load()
 	return a
 }
 
-func (a *Acquirer) AdjustHoldOff(delta int) {
+func (a *Acquirer) AdjustHoldOff(delta time.Duration) {
 	for {
 		old := a.HoldOff.Load()
 		next := max(old+int64(delta), 0)
@@ -39,45 +74,191 @@ func (a *Acquirer) AdjustHoldOff(delta int) {
 	}
 }
 
-func (a *Acquirer) Build(ring *memory.Ring) Result {
-	if ring.Count() < int(math.Floor(SamplesPerRecord)) {
+// SetHoldOff replaces the time holdoff; negative values count as zero.
+func (a *Acquirer) SetHoldOff(d time.Duration) {
+	a.HoldOff.Store(int64(max(d, 0)))
+}
+
+// SetRecordLength changes the time span of each record.
+func (a *Acquirer) SetRecordLength(d time.Duration) {
+	a.RecordLength.Store(int64(max(d, MinRecordLength)))
+}
+
+func (a *Acquirer) SetGain(g float64) {
+	a.Gain.Store(math.Float64bits(g))
+}
+
+func (a *Acquirer) SetOffset(v float64) {
+	a.Offset.Store(math.Float64bits(v))
+}
+
+// SetTrigger requests new trigger settings; they take effect from the
+// next Build.
+func (a *Acquirer) SetTrigger(t trigger.Trigger) {
+	a.triggerSettings.Store(&t)
+}
+
+func (a *Acquirer) AdjustHoldOffEvents(delta int) {
+	for {
+		old := a.HoldOffEvents.Load()
+		next := max(old+int64(delta), 0)
+		if a.HoldOffEvents.CompareAndSwap(old, next) {
+			return
+		}
+	}
+}
+
+func (a *Acquirer) SetHoldOffMode(mode HoldOffMode) {
+	a.HoldOffMode.Store(int32(mode))
+}
+
+func (a *Acquirer) SetMode(mode Mode) {
+	a.Mode.Store(int32(mode))
+}
+
+// SetBuckets sets the number of decimation buckets used in peak-detect
+// mode, normally the display width in pixels.
+func (a *Acquirer) SetBuckets(n int) {
+	a.Buckets.Store(int64(n))
+}
+
+func (a *Acquirer) SetAverageKind(kind AverageKind) {
+	a.AverageKind.Store(int32(kind))
+}
+
+func (a *Acquirer) SetAverageCount(n int) {
+	a.AverageCount.Store(int64(max(n, 1)))
+}
+
+func (a *Acquirer) SetHighResWidth(n int) {
+	a.HighResWidth.Store(int64(max(n, 1)))
+}
+
+// SetEnvelopeHold sets how many records the envelope spans; zero holds
+// every record since the last reset.
+func (a *Acquirer) SetEnvelopeHold(n int) {
+	a.EnvelopeHold.Store(int64(max(n, 0)))
+}
+
+// Build searches for a trigger in the samples up to and including newest, as
+// published by the producer, and returns the record around it.
+func (a *Acquirer) Build(ring *memory.Ring, newest int) Result {
+	if m := a.GetMode(); m == ModeRoll || m == ModeWaterfall {
 		return a.Empty()
 	}
 
-	searchStart := ring.OldestIndex() + int(math.Floor(PreSamples))
-	searchEnd := ring.NewestIndex() - int(math.Floor(PreSamples))
+	if t := a.triggerSettings.Load(); t != a.appliedTrigger {
+		*a.Trigger = *t
+		a.appliedTrigger = t
+		a.resume = 0
+	}
 
-	trig, ok := a.Trigger.Find(ring, searchStart, searchEnd)
-	if !ok {
+	bounds, ok := ring.Snapshot()
+	if !ok || bounds.Count() < a.SamplesPerRecord() {
 		return a.Empty()
 	}
 
-	if trig.Index-a.LastTriggerIndex < int(a.HoldOff.Load()) {
+	searchStart := bounds.Oldest + a.PreSamples()
+	searchEnd := min(newest, bounds.Newest) - a.PreSamples()
+
+	trig, ok := a.findTrigger(ring, searchStart, searchEnd)
+	if !ok {
 		return a.Empty()
 	}
 
-	recordStart := trig.Index - int(math.Floor(PreSamples))
-	recordEnd := recordStart + int(math.Floor(SamplesPerRecord))
+	recordStart := trig.Index - a.PreSamples()
+	recordEnd := recordStart + a.SamplesPerRecord()
 
 	if !ring.HasRange(recordStart, recordEnd) {
 		return a.Empty()
 	}
 
-	samples, err := ring.ReadRange(recordStart, recordEnd)
-	if err != nil {
+	// Every record gets its own buffer: the hub hands the same record to
+	// several subscribers, and the segment store and display keep records
+	// after the next Build, so there is no point at which a pooled buffer
+	// could be reused safely.
+	samples := make([]float32, recordEnd-recordStart)
+	if err := ring.ReadInto(samples, recordStart); err != nil {
 		return a.Empty()
 	}
 
 	a.LastTriggerIndex = trig.Index
+	a.sequence++
+
+	rec := record.Record{
+		Samples:       samples,
+		TriggerIndex:  a.PreSamples(),
+		TriggerOffset: trig.Offset,
+		StartIndex:    recordStart,
+		SampleRate:    a.Stream.SampleRate,
+		Channel:       a.Channel,
+		Time:          a.TriggerTime(ring, time.Now()),
+		Trigger:       *a.Trigger,
+		Mode:          a.GetMode().String(),
+		Sequence:      a.sequence,
+	}
+
+	if a.GetMode() == ModePeakDetect {
+		rec.Min, rec.Max = PeakDetect(samples, int(a.Buckets.Load()))
+	}
 
 	return Result{
-		Record: record.Record{
-			Samples:       samples,
-			TriggerIndex:  int(math.Floor(PreSamples)),
-			TriggerOffset: trig.Offset,
-		},
-		Ready: true,
+		Record: rec,
+		Ready:  true,
+	}
+}
+
+// findTrigger returns the first trigger in [start, end) that satisfies the
+// holdoff relative to LastTriggerIndex.
+func (a *Acquirer) findTrigger(ring *memory.Ring, start, end int) (trigger.Result, bool) {
+	if a.GetHoldOffMode() == HoldOffEvents {
+		a.resume = 0
+
+		skip := a.HoldOffEvents.Load()
+		if a.LastTriggerIndex < 0 {
+			skip = 0
+		}
+
+		start = max(start, a.LastTriggerIndex+1)
+		for {
+			trig, ok := a.Trigger.Find(ring, start, end)
+			if !ok || skip == 0 {
+				return trig, ok
+			}
+			skip--
+			start = trig.Index + 1
+		}
+	}
+
+	start = max(start, a.LastTriggerIndex+max(a.HoldOffSamples(), 1), a.resume)
+	trig, resume, ok := a.Trigger.FindResume(ring, start, end)
+	if ok {
+		a.resume = 0
+	} else {
+		a.resume = resume
 	}
+	return trig, ok
+}
+
+// HoldOffSamples converts the holdoff time to samples at the current
+// sample rate.
+func (a *Acquirer) HoldOffSamples() int {
+	return a.Stream.Samples(a.GetHoldOff())
+}
+
+// SamplesPerRecord is the record length at the stream's sample rate.
+func (a *Acquirer) SamplesPerRecord() int {
+	return a.Stream.Samples(a.GetRecordLength())
+}
+
+func (a *Acquirer) PreSamples() int {
+	return int(float64(a.SamplesPerRecord()) * preTriggerRatio)
+}
+
+// TriggerTime estimates the wall-clock time of the last accepted trigger
+// from how far it lies behind the newest sample in the ring.
+func (a *Acquirer) TriggerTime(ring *memory.Ring, now time.Time) time.Time {
+	return now.Add(-a.Stream.Duration(ring.NewestIndex() - a.LastTriggerIndex))
 }
 
 func (a *Acquirer) Empty() Result {
@@ -87,6 +268,52 @@ func (a *Acquirer) Empty() Result {
 	}
 }
 
-func (a *Acquirer) GetHoldOff() int {
-	return int(a.HoldOff.Load())
+func (a *Acquirer) GetHoldOff() time.Duration {
+	return time.Duration(a.HoldOff.Load())
+}
+
+func (a *Acquirer) GetHoldOffEvents() int {
+	return int(a.HoldOffEvents.Load())
+}
+
+func (a *Acquirer) GetMode() Mode {
+	return Mode(a.Mode.Load())
+}
+
+func (a *Acquirer) GetAverageKind() AverageKind {
+	return AverageKind(a.AverageKind.Load())
+}
+
+func (a *Acquirer) GetAverageCount() int {
+	return int(a.AverageCount.Load())
+}
+
+func (a *Acquirer) GetHighResWidth() int {
+	return int(a.HighResWidth.Load())
+}
+
+func (a *Acquirer) GetEnvelopeHold() int {
+	return int(a.EnvelopeHold.Load())
+}
+
+func (a *Acquirer) GetRecordLength() time.Duration {
+	return time.Duration(a.RecordLength.Load())
+}
+
+func (a *Acquirer) GetGain() float64 {
+	return math.Float64frombits(a.Gain.Load())
+}
+
+func (a *Acquirer) GetOffset() float64 {
+	return math.Float64frombits(a.Offset.Load())
+}
+
+// GetTrigger returns the latest trigger settings, including any not yet
+// applied by Build.
+func (a *Acquirer) GetTrigger() trigger.Trigger {
+	return *a.triggerSettings.Load()
+}
+
+func (a *Acquirer) GetHoldOffMode() HoldOffMode {
+	return HoldOffMode(a.HoldOffMode.Load())
 }
@@ -1,20 +1,124 @@
 package acquisition
 
-import "oscilloscope/internal/source"
+import "time"
 
-const milliSecond = source.SampleRate / 1000
 const preTriggerRatio = 0.0
 
 var (
 	BPM           = 120.0
 	QuarterBeatMs = convertBPM(BPM)
+	RecordMs      = QuarterBeatMs / 2
 
-	SamplesPerRecord = milliSecond * QuarterBeatMs / 2
+	RecordDuration = time.Duration(RecordMs * float64(time.Millisecond))
 
-	DefaultHoldOff = SamplesPerRecord
-	PreSamples     = SamplesPerRecord * preTriggerRatio
+	DefaultHoldOff       = RecordDuration
+	DefaultHoldOffEvents = 0
+	DefaultAverageCount  = 16
+	DefaultHighResWidth  = 8
+	DefaultEnvelopeHold  = 16
+	DefaultSegmentCount  = 64
+	DefaultGain          = 1.0
+
+	MinRecordLength = time.Millisecond
 )
 
 func convertBPM(bpm float64) float64 {
 	return (60.0 / bpm) * 1000.0
 }
+
+type HoldOffMode int32
+
+const (
+	// HoldOffTime ignores triggers until a fixed time has passed since the
+	// last accepted one.
+	HoldOffTime HoldOffMode = iota
+	// HoldOffEvents skips a fixed number of trigger events after the last
+	// accepted one.
+	HoldOffEvents
+)
+
+func (m HoldOffMode) String() string {
+	switch m {
+	case HoldOffEvents:
+		return "events"
+	default:
+		return "time"
+	}
+}
+
+type Mode int32
+
+const (
+	ModeNormal Mode = iota
+	// ModePeakDetect keeps the min/max of every decimation bucket so
+	// features narrower than a display column survive.
+	ModePeakDetect
+	// ModeAverage averages successive triggered records.
+	ModeAverage
+	// ModeHighRes box-car averages adjacent samples within a record.
+	ModeHighRes
+	// ModeEnvelope tracks the per-sample min/max across successive
+	// triggered records.
+	ModeEnvelope
+	// ModeRoll skips trigger search; the display streams straight from the
+	// ring instead of waiting for records.
+	ModeRoll
+	// ModeSegmented captures consecutive triggered records back to back
+	// into a SegmentStore.
+	ModeSegmented
+	// ModeSpectrum acquires like ModeNormal; the display shows the
+	// spectrum of each record instead of the trace.
+	ModeSpectrum
+	// ModeWaterfall, like ModeRoll, skips trigger search; the display
+	// analyses the ring continuously.
+	ModeWaterfall
+
+	modeCount
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModePeakDetect:
+		return "peak detect"
+	case ModeAverage:
+		return "average"
+	case ModeHighRes:
+		return "high res"
+	case ModeEnvelope:
+		return "envelope"
+	case ModeRoll:
+		return "roll"
+	case ModeSegmented:
+		return "segmented"
+	case ModeSpectrum:
+		return "spectrum"
+	case ModeWaterfall:
+		return "waterfall"
+	default:
+		return "normal"
+	}
+}
+
+// Next returns the mode after m, wrapping around.
+func (m Mode) Next() Mode {
+	return (m + 1) % modeCount
+}
+
+type AverageKind int32
+
+const (
+	// AverageExponential blends each record into a running average with
+	// weight 1/N and publishes every record.
+	AverageExponential AverageKind = iota
+	// AverageBlock sums N records and publishes their mean once per block.
+	AverageBlock
+)
+
+func (k AverageKind) String() string {
+	switch k {
+	case AverageBlock:
+		return "block"
+	default:
+		return "exponential"
+	}
+}
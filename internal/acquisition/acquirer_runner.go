@@ -1,53 +1,120 @@
 package acquisition
 
 import (
-	"sync"
+	"context"
 
+	"oscilloscope/internal/hub"
 	"oscilloscope/internal/memory"
 	"oscilloscope/internal/record"
 )
 
 type AcquirerRunner struct {
-	Ring     *memory.Ring
-	Acquirer *Acquirer
-	Cond     *sync.Cond
+	Ring      *memory.Ring
+	Acquirer  *Acquirer
+	Processor *Processor
+	Segments  *SegmentStore
+	Notifier  *memory.Notifier
 
-	Out  chan record.Record
-	Done chan struct{}
+	// Siblings are the rings of the other input channels, in channel
+	// order. Every record carries their samples over its own span.
+	Siblings []*memory.Ring
+
+	Hub *hub.Hub
 }
 
-func NewRunner(ring *memory.Ring, acquirer *Acquirer, cond *sync.Cond, out chan record.Record, done chan struct{}) *AcquirerRunner {
+func NewRunner(ring *memory.Ring, acquirer *Acquirer, processor *Processor, segments *SegmentStore, notifier *memory.Notifier, h *hub.Hub) *AcquirerRunner {
 	return &AcquirerRunner{
-		Ring:     ring,
-		Acquirer: acquirer,
-		Cond:     cond,
-		Out:      out,
-		Done:     done,
+		Ring:      ring,
+		Acquirer:  acquirer,
+		Processor: processor,
+		Segments:  segments,
+		Notifier:  notifier,
+		Hub:       h,
 	}
 }
 
-func (ar *AcquirerRunner) Run() {
+// Run builds and publishes records until ctx is cancelled, then closes the
+// hub so every subscriber sees the end of the stream.
+func (ar *AcquirerRunner) Run(ctx context.Context) error {
+	defer ar.Hub.Close()
+
+	waiter := ar.Notifier.NewWaiter()
+	defer waiter.Close()
+
+	seen := -1
 	for {
-		ar.Cond.L.Lock()
-		ar.Cond.Wait()
-		ar.Cond.L.Unlock()
+		newest, ok := waiter.Wait(ctx.Done(), seen)
+		if !ok {
+			return nil
+		}
+		seen = newest
 
-		select {
-		case <-ar.Done:
-			return
-		default:
+		if ar.Acquirer.GetMode() == ModeSegmented {
+			ar.captureSegments(newest)
+			continue
+		}
+
+		res := ar.Acquirer.Build(ar.Ring, newest)
+		if !res.Ready {
+			continue
+		}
+
+		rec, ok := ar.withSiblings(res.Record)
+		if !ok {
+			continue
+		}
+
+		rec, ok = ar.Processor.Process(rec)
+		if !ok {
+			continue
 		}
 
-		res := ar.Acquirer.Build(ar.Ring)
+		ar.Hub.Publish(rec)
+	}
+}
+
+// captureSegments stores every trigger available in the ring, rearming
+// straight away instead of waiting for the display to consume a record.
+func (ar *AcquirerRunner) captureSegments(newest int) {
+	for !ar.Segments.Full() {
+		res := ar.Acquirer.Build(ar.Ring, newest)
 		if !res.Ready {
+			return
+		}
+
+		rec, ok := ar.withSiblings(res.Record)
+		if !ok {
 			continue
 		}
 
-		select {
-		case ar.Out <- res.Record:
-		default:
-			<-ar.Out
-			ar.Out <- res.Record
+		ar.Segments.Add(Segment{
+			Record:       rec,
+			TriggerIndex: rec.StartIndex + rec.TriggerIndex,
+			Time:         rec.Time,
+		})
+	}
+}
+
+// withSiblings reads every other channel over the span of rec. It fails if
+// one of them no longer holds that span.
+func (ar *AcquirerRunner) withSiblings(rec record.Record) (record.Record, bool) {
+	if len(ar.Siblings) == 0 {
+		return rec, true
+	}
+
+	rec.Siblings = make([]record.Record, len(ar.Siblings))
+	for i, ring := range ar.Siblings {
+		sib := rec
+		sib.Channel = rec.Channel + 1 + i
+		sib.Siblings = nil
+		sib.Samples = make([]float32, len(rec.Samples))
+		if err := ring.ReadInto(sib.Samples, rec.StartIndex); err != nil {
+			return record.Record{}, false
+		}
+		if rec.Min != nil {
+			sib.Min, sib.Max = PeakDetect(sib.Samples, len(rec.Max))
 		}
+		rec.Siblings[i] = sib
 	}
+	return rec, true
 }
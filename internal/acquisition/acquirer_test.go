@@ -0,0 +1,135 @@
+package acquisition
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"oscilloscope/internal/memory"
+	"oscilloscope/internal/source"
+	"oscilloscope/internal/trigger"
+)
+
+const testPeriod = 100
+
+func sineRing(n int) *memory.Ring {
+	ring := memory.New(memory.MemoryBufferSize)
+	buf := make([]float32, n)
+	for i := range buf {
+		buf[i] = float32(math.Sin(2 * math.Pi * float64(i) / testPeriod))
+	}
+	ring.WriteBatch(0, buf)
+	return ring
+}
+
+func TestHoldOffEventsSkipsTriggers(t *testing.T) {
+	ring := sineRing(memory.MemoryBufferSize)
+
+	a := New(trigger.New(), source.DefaultStream())
+	a.SetHoldOffMode(HoldOffEvents)
+	a.HoldOffEvents.Store(2)
+
+	first := a.Build(ring, ring.NewestIndex())
+	if !first.Ready {
+		t.Fatal("expected first record")
+	}
+	firstIndex := a.LastTriggerIndex
+
+	second := a.Build(ring, ring.NewestIndex())
+	if !second.Ready {
+		t.Fatal("expected second record")
+	}
+
+	if got, want := a.LastTriggerIndex-firstIndex, 3*testPeriod; got != want {
+		t.Fatalf("trigger distance = %d, want %d", got, want)
+	}
+}
+
+func TestHoldOffTimeFollowsSampleRate(t *testing.T) {
+	ring := sineRing(memory.MemoryBufferSize)
+
+	a := New(trigger.New(), source.DefaultStream())
+	a.HoldOff.Store(int64(10 * time.Millisecond))
+
+	a.Stream.SampleRate = 10000
+	if got, want := a.HoldOffSamples(), 100; got != want {
+		t.Fatalf("holdoff at 10 kHz = %d samples, want %d", got, want)
+	}
+
+	a.Stream.SampleRate = 20000
+	if got, want := a.HoldOffSamples(), 200; got != want {
+		t.Fatalf("holdoff at 20 kHz = %d samples, want %d", got, want)
+	}
+
+	a.Build(ring, ring.NewestIndex())
+	firstIndex := a.LastTriggerIndex
+	a.Build(ring, ring.NewestIndex())
+
+	if got := a.LastTriggerIndex - firstIndex; got < 200 {
+		t.Fatalf("trigger distance = %d, want at least 200", got)
+	}
+}
+
+func TestBuildFillsMetadata(t *testing.T) {
+	ring := sineRing(memory.MemoryBufferSize)
+
+	a := New(trigger.New(), source.DefaultStream())
+	a.SetHoldOffMode(HoldOffEvents)
+
+	first := a.Build(ring, ring.NewestIndex()).Record
+	second := a.Build(ring, ring.NewestIndex()).Record
+
+	if first.StartIndex+first.TriggerIndex != a.LastTriggerIndex-testPeriod {
+		t.Fatalf("first record starts at %d, trigger at %d", first.StartIndex, a.LastTriggerIndex)
+	}
+	if first.SampleRate != a.Stream.SampleRate || first.Mode != ModeNormal.String() {
+		t.Fatalf("metadata = rate %d mode %q", first.SampleRate, first.Mode)
+	}
+	if first.Trigger != *a.Trigger {
+		t.Fatalf("trigger snapshot = %+v, want %+v", first.Trigger, *a.Trigger)
+	}
+	if second.Sequence != first.Sequence+1 || second.Missed(first) != 0 {
+		t.Fatalf("sequence %d after %d", second.Sequence, first.Sequence)
+	}
+	if first.Time.IsZero() {
+		t.Fatal("capture time not set")
+	}
+}
+
+func TestBuildResumesAcrossWakeups(t *testing.T) {
+	full := sineRing(memory.MemoryBufferSize)
+	whole := New(trigger.New(), source.DefaultStream())
+	whole.HoldOff.Store(int64(time.Millisecond))
+	whole.Build(full, full.NewestIndex())
+	want := whole.LastTriggerIndex
+
+	src, _ := full.ReadRange(0, full.NewestIndex())
+	ring := memory.New(memory.MemoryBufferSize)
+	chunked := New(trigger.New(), source.DefaultStream())
+	chunked.HoldOff.Store(int64(time.Millisecond))
+
+	const chunk = 37
+	for i := 0; i < len(src) && chunked.LastTriggerIndex < 0; i += chunk {
+		end := min(i+chunk, len(src))
+		ring.WriteBatch(i, src[i:end])
+		chunked.Build(ring, end-1)
+	}
+
+	if chunked.LastTriggerIndex != want {
+		t.Fatalf("chunked trigger at %d, full scan at %d", chunked.LastTriggerIndex, want)
+	}
+}
+
+func BenchmarkBuild(b *testing.B) {
+	ring := sineRing(memory.MemoryBufferSize)
+	a := New(trigger.New(), source.DefaultStream())
+	a.SetHoldOffMode(HoldOffEvents)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		a.LastTriggerIndex = -1
+		if !a.Build(ring, ring.NewestIndex()).Ready {
+			b.Fatal("no record")
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package acquisition
+
+// PeakDetect splits samples into the given number of contiguous buckets and
+// returns the minimum and maximum of each. A bucket count of zero or one
+// larger than the sample count keeps one sample per bucket.
+func PeakDetect(samples []float32, buckets int) (lo, hi []float32) {
+	n := len(samples)
+	if n == 0 {
+		return nil, nil
+	}
+	if buckets <= 0 || buckets > n {
+		buckets = n
+	}
+
+	lo = make([]float32, buckets)
+	hi = make([]float32, buckets)
+
+	for b := range buckets {
+		start := b * n / buckets
+		end := (b + 1) * n / buckets
+
+		mn, mx := samples[start], samples[start]
+		for _, v := range samples[start+1 : end] {
+			mn = min(mn, v)
+			mx = max(mx, v)
+		}
+
+		lo[b] = mn
+		hi[b] = mx
+	}
+
+	return lo, hi
+}
@@ -0,0 +1,36 @@
+package acquisition
+
+import "testing"
+
+func TestPeakDetectKeepsNarrowGlitch(t *testing.T) {
+	samples := make([]float32, 1000)
+	samples[503] = 0.8
+	samples[777] = -0.6
+
+	lo, hi := PeakDetect(samples, 10)
+
+	if len(lo) != 10 || len(hi) != 10 {
+		t.Fatalf("got %d/%d buckets, want 10", len(lo), len(hi))
+	}
+	if hi[5] != 0.8 {
+		t.Fatalf("bucket 5 max = %f, want 0.8", hi[5])
+	}
+	if lo[7] != -0.6 {
+		t.Fatalf("bucket 7 min = %f, want -0.6", lo[7])
+	}
+	if hi[4] != 0 || lo[4] != 0 {
+		t.Fatalf("bucket 4 = [%f, %f], want [0, 0]", lo[4], hi[4])
+	}
+}
+
+func TestPeakDetectWithoutDecimation(t *testing.T) {
+	samples := []float32{0.1, -0.2, 0.3}
+
+	lo, hi := PeakDetect(samples, 0)
+
+	for i, v := range samples {
+		if lo[i] != v || hi[i] != v {
+			t.Fatalf("bucket %d = [%f, %f], want %f", i, lo[i], hi[i], v)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package acquisition
+
+import (
+	"slices"
+	"sync/atomic"
+
+	"oscilloscope/internal/record"
+	"oscilloscope/internal/trigger"
+)
+
+// Processor is the stage between Acquirer.Build and the record channel. It
+// applies the averaging, high-resolution and envelope modes, and starts over
+// whenever the trigger, timebase or processing settings change.
+type Processor struct {
+	Acquirer *Acquirer
+
+	resetPending atomic.Bool
+	settings     settings
+	sum          []float64
+	n            int
+
+	history [][]float32
+	envMin  []float32
+	envMax  []float32
+
+	siblings []*Processor // state of each sibling channel
+}
+
+// settings is the snapshot of everything that invalidates an average.
+type settings struct {
+	polarity     trigger.Polarity
+	level        float64
+	lower        float64
+	upper        float64
+	samples      int
+	preSamples   int
+	mode         Mode
+	averageKind  AverageKind
+	averageCount int
+	highResWidth int
+	envelopeHold int
+}
+
+func NewProcessor(acquirer *Acquirer) *Processor {
+	return &Processor{Acquirer: acquirer}
+}
+
+// Reset discards the accumulated average and envelope. It is safe to call from any
+// goroutine; the reset takes effect on the next processed record.
+func (p *Processor) Reset() {
+	p.resetPending.Store(true)
+}
+
+// Process returns the record to publish and whether one is ready. Block
+// averaging only produces a record once every N inputs. Sibling records
+// are processed alongside rec, each channel with its own state.
+func (p *Processor) Process(rec record.Record) (record.Record, bool) {
+	reset := p.resetPending.Swap(false)
+
+	siblings := make([]record.Record, len(rec.Siblings))
+	for i, sib := range rec.Siblings {
+		siblings[i], _ = p.sibling(i).process(sib, reset)
+	}
+
+	out, ok := p.process(rec, reset)
+	if ok && len(siblings) > 0 {
+		out.Siblings = siblings
+	}
+	return out, ok
+}
+
+func (p *Processor) sibling(i int) *Processor {
+	for len(p.siblings) <= i {
+		p.siblings = append(p.siblings, NewProcessor(p.Acquirer))
+	}
+	return p.siblings[i]
+}
+
+func (p *Processor) process(rec record.Record, reset bool) (record.Record, bool) {
+	s := p.snapshot()
+	if reset || s != p.settings || len(rec.Samples) != len(p.sum) {
+		p.settings = s
+		p.clear(len(rec.Samples))
+	}
+
+	switch s.mode {
+	case ModeAverage:
+		return p.average(rec)
+	case ModeHighRes:
+		rec.Samples = BoxCar(rec.Samples, s.highResWidth)
+		return rec, true
+	case ModeEnvelope:
+		return p.envelope(rec), true
+	default:
+		return rec, true
+	}
+}
+
+func (p *Processor) average(rec record.Record) (record.Record, bool) {
+	count := p.settings.averageCount
+
+	if p.settings.averageKind == AverageBlock {
+		for i, v := range rec.Samples {
+			p.sum[i] += float64(v)
+		}
+		p.n++
+
+		if p.n < count {
+			return record.Record{}, false
+		}
+
+		out := make([]float32, len(p.sum))
+		for i, v := range p.sum {
+			out[i] = float32(v / float64(p.n))
+			p.sum[i] = 0
+		}
+		p.n = 0
+
+		rec.Samples = out
+		return rec, true
+	}
+
+	// Exponential: the weight ramps from 1 to 1/N so the first records
+	// are not biased towards zero.
+	p.n = min(p.n+1, count)
+	weight := 1 / float64(p.n)
+
+	out := make([]float32, len(p.sum))
+	for i, v := range rec.Samples {
+		p.sum[i] += (float64(v) - p.sum[i]) * weight
+		out[i] = float32(p.sum[i])
+	}
+
+	rec.Samples = out
+	return rec, true
+}
+
+func (p *Processor) envelope(rec record.Record) record.Record {
+	hold := p.settings.envelopeHold
+
+	if hold > 0 {
+		p.history = append(p.history, rec.Samples)
+		if len(p.history) > hold {
+			p.history = p.history[1:]
+		}
+
+		p.envMin = slices.Clone(p.history[0])
+		p.envMax = slices.Clone(p.history[0])
+		for _, samples := range p.history[1:] {
+			for i, v := range samples {
+				p.envMin[i] = min(p.envMin[i], v)
+				p.envMax[i] = max(p.envMax[i], v)
+			}
+		}
+	} else if p.envMin == nil {
+		p.envMin = slices.Clone(rec.Samples)
+		p.envMax = slices.Clone(rec.Samples)
+	} else {
+		for i, v := range rec.Samples {
+			p.envMin[i] = min(p.envMin[i], v)
+			p.envMax[i] = max(p.envMax[i], v)
+		}
+	}
+
+	rec.EnvelopeMin = slices.Clone(p.envMin)
+	rec.EnvelopeMax = slices.Clone(p.envMax)
+	return rec
+}
+
+func (p *Processor) clear(n int) {
+	p.sum = make([]float64, n)
+	p.n = 0
+	p.history = nil
+	p.envMin = nil
+	p.envMax = nil
+}
+
+func (p *Processor) snapshot() settings {
+	a := p.Acquirer
+	return settings{
+		polarity:     a.Trigger.Polarity,
+		level:        a.Trigger.Level,
+		lower:        a.Trigger.Lower,
+		upper:        a.Trigger.Upper,
+		samples:      a.SamplesPerRecord(),
+		preSamples:   a.PreSamples(),
+		mode:         a.GetMode(),
+		averageKind:  a.GetAverageKind(),
+		averageCount: a.GetAverageCount(),
+		highResWidth: a.GetHighResWidth(),
+		envelopeHold: a.GetEnvelopeHold(),
+	}
+}
+
+// BoxCar replaces every sample with the mean of the width samples centred
+// on it, shrinking the window at the record edges.
+func BoxCar(samples []float32, width int) []float32 {
+	out := make([]float32, len(samples))
+	if width <= 1 {
+		copy(out, samples)
+		return out
+	}
+
+	half := width / 2
+	var sum float64
+	lo, hi := 0, 0
+
+	for i := range samples {
+		for hi < len(samples) && hi <= i-half+width-1 {
+			sum += float64(samples[hi])
+			hi++
+		}
+		for lo < i-half {
+			sum -= float64(samples[lo])
+			lo++
+		}
+		out[i] = float32(sum / float64(hi-lo))
+	}
+
+	return out
+}
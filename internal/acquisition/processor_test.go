@@ -0,0 +1,100 @@
+package acquisition
+
+import (
+	"testing"
+
+	"oscilloscope/internal/record"
+	"oscilloscope/internal/source"
+	"oscilloscope/internal/trigger"
+)
+
+func TestProcessorBlockAverage(t *testing.T) {
+	a := New(trigger.New(), source.DefaultStream())
+	a.SetMode(ModeAverage)
+	a.SetAverageKind(AverageBlock)
+	a.SetAverageCount(4)
+	p := NewProcessor(a)
+
+	var out record.Record
+	for i := range 4 {
+		rec, ok := p.Process(record.Record{Samples: []float32{float32(i), 1}})
+		if ok != (i == 3) {
+			t.Fatalf("record %d: ready = %v", i, ok)
+		}
+		out = rec
+	}
+
+	if out.Samples[0] != 1.5 || out.Samples[1] != 1 {
+		t.Fatalf("block average = %v, want [1.5 1]", out.Samples)
+	}
+}
+
+func TestProcessorResetsOnTriggerChange(t *testing.T) {
+	a := New(trigger.New(), source.DefaultStream())
+	a.SetMode(ModeAverage)
+	a.SetAverageCount(2)
+	p := NewProcessor(a)
+
+	p.Process(record.Record{Samples: []float32{1}})
+	a.Trigger.Polarity = trigger.Negative
+	rec, _ := p.Process(record.Record{Samples: []float32{-1}})
+
+	if rec.Samples[0] != -1 {
+		t.Fatalf("after trigger change got %f, want -1", rec.Samples[0])
+	}
+}
+
+func TestProcessorAveragesSiblingsSeparately(t *testing.T) {
+	a := New(trigger.New(), source.DefaultStream())
+	a.SetMode(ModeAverage)
+	a.SetAverageKind(AverageBlock)
+	a.SetAverageCount(2)
+	p := NewProcessor(a)
+
+	var out record.Record
+	for i := range 2 {
+		rec := record.Record{Samples: []float32{float32(i)}}
+		rec.Siblings = []record.Record{{Channel: 1, Samples: []float32{float32(10 * i)}}}
+		out, _ = p.Process(rec)
+	}
+
+	if out.Samples[0] != 0.5 || len(out.Siblings) != 1 || out.Siblings[0].Samples[0] != 5 {
+		t.Fatalf("averages = %v and %v, want 0.5 and 5", out.Samples, out.Siblings)
+	}
+}
+
+func TestBoxCar(t *testing.T) {
+	got := BoxCar([]float32{0, 3, 0, 3, 0}, 3)
+	want := []float32{1.5, 1, 2, 1, 1.5}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("BoxCar = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestProcessorEnvelopeHold(t *testing.T) {
+	a := New(trigger.New(), source.DefaultStream())
+	a.SetMode(ModeEnvelope)
+	a.SetEnvelopeHold(2)
+	p := NewProcessor(a)
+
+	p.Process(record.Record{Samples: []float32{-1, 1}})
+	p.Process(record.Record{Samples: []float32{0, 0}})
+	rec, _ := p.Process(record.Record{Samples: []float32{0.5, -0.5}})
+
+	if rec.EnvelopeMin[0] != 0 || rec.EnvelopeMax[1] != 0 {
+		t.Fatalf("envelope kept an expired record: min %v max %v", rec.EnvelopeMin, rec.EnvelopeMax)
+	}
+
+	a.SetEnvelopeHold(0)
+	p.Process(record.Record{Samples: []float32{-1, 1}})
+	for range 3 {
+		rec, _ = p.Process(record.Record{Samples: []float32{0, 0}})
+	}
+
+	if rec.EnvelopeMin[0] != -1 || rec.EnvelopeMax[1] != 1 {
+		t.Fatalf("infinite hold lost extremes: min %v max %v", rec.EnvelopeMin, rec.EnvelopeMax)
+	}
+}
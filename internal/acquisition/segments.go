@@ -0,0 +1,96 @@
+package acquisition
+
+import (
+	"sync"
+	"time"
+
+	"oscilloscope/internal/record"
+)
+
+type Segment struct {
+	Record       record.Record
+	TriggerIndex int // absolute ring index of the trigger
+	Time         time.Time
+}
+
+// SegmentStore keeps up to a fixed number of consecutive triggered records.
+// Capture stops once it is full until it is cleared.
+type SegmentStore struct {
+	mu       sync.Mutex
+	capacity int
+	segments []Segment
+	version  uint64
+}
+
+func NewSegmentStore(capacity int) *SegmentStore {
+	return &SegmentStore{
+		capacity: capacity,
+		segments: make([]Segment, 0, capacity),
+	}
+}
+
+// Add appends a segment and reports whether there was room for it.
+func (s *SegmentStore) Add(seg Segment) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.segments) >= s.capacity {
+		return false
+	}
+
+	s.segments = append(s.segments, seg)
+	s.version++
+	return true
+}
+
+func (s *SegmentStore) At(i int) (Segment, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i < 0 || i >= len(s.segments) {
+		return Segment{}, false
+	}
+	return s.segments[i], true
+}
+
+// All returns a snapshot of the stored segments, oldest first.
+func (s *SegmentStore) All() []Segment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]Segment, len(s.segments))
+	copy(out, s.segments)
+	return out
+}
+
+func (s *SegmentStore) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.segments = s.segments[:0]
+	s.version++
+}
+
+func (s *SegmentStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.segments)
+}
+
+func (s *SegmentStore) Full() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.segments) >= s.capacity
+}
+
+// Version changes every time a segment is added or the store is cleared.
+func (s *SegmentStore) Version() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.version
+}
+
+func (s *SegmentStore) Capacity() int { return s.capacity }
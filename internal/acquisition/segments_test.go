@@ -0,0 +1,75 @@
+package acquisition
+
+import (
+	"testing"
+
+	"oscilloscope/internal/memory"
+	"oscilloscope/internal/source"
+	"oscilloscope/internal/trigger"
+)
+
+func TestCaptureSegmentsRearmsImmediately(t *testing.T) {
+	ring := sineRing(memory.MemoryBufferSize)
+
+	a := New(trigger.New(), source.DefaultStream())
+	a.SetMode(ModeSegmented)
+	a.SetHoldOffMode(HoldOffEvents)
+
+	segments := NewSegmentStore(3)
+	ar := NewRunner(ring, a, NewProcessor(a), segments, memory.NewNotifier(), nil)
+
+	ar.captureSegments(ring.NewestIndex())
+
+	if segments.Len() != 3 || !segments.Full() {
+		t.Fatalf("captured %d segments in one wakeup, want 3", segments.Len())
+	}
+
+	all := segments.All()
+	for i := 1; i < len(all); i++ {
+		if got := all[i].TriggerIndex - all[i-1].TriggerIndex; got != testPeriod {
+			t.Fatalf("segment %d trigger distance = %d, want %d", i, got, testPeriod)
+		}
+		if all[i].Time.Sub(all[i-1].Time) <= 0 {
+			t.Fatalf("segment %d timestamp not after previous", i)
+		}
+	}
+
+	segments.Clear()
+	if segments.Len() != 0 {
+		t.Fatal("clear left segments behind")
+	}
+}
+
+func TestSegmentsCarrySiblings(t *testing.T) {
+	ring := sineRing(memory.MemoryBufferSize)
+	src, _ := ring.ReadRange(0, ring.NewestIndex())
+	for i := range src {
+		src[i] = -src[i]
+	}
+	sibling := memory.New(memory.MemoryBufferSize)
+	sibling.WriteBatch(0, src)
+
+	a := New(trigger.New(), source.DefaultStream())
+	a.SetMode(ModeSegmented)
+	a.SetHoldOffMode(HoldOffEvents)
+
+	segments := NewSegmentStore(1)
+	ar := NewRunner(ring, a, NewProcessor(a), segments, memory.NewNotifier(), nil)
+	ar.Siblings = []*memory.Ring{sibling}
+
+	ar.captureSegments(ring.NewestIndex())
+
+	seg, ok := segments.At(0)
+	if !ok || len(seg.Record.Siblings) != 1 {
+		t.Fatal("segment has no sibling record")
+	}
+	sib := seg.Record.Siblings[0]
+	if sib.Channel != 1 || sib.StartIndex != seg.Record.StartIndex {
+		t.Fatalf("sibling channel %d at %d, want 1 at %d", sib.Channel, sib.StartIndex, seg.Record.StartIndex)
+	}
+	for i, v := range seg.Record.Samples {
+		if sib.Samples[i] != -v {
+			t.Fatalf("sibling sample %d = %g, want %g", i, sib.Samples[i], -v)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package analysis
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"oscilloscope/internal/acquisition"
+	"oscilloscope/internal/memory"
+	"oscilloscope/internal/trigger"
+)
+
+var (
+	errNoSignal   = errors.New("analysis: no signal")
+	errShortInput = errors.New("analysis: not enough samples")
+
+	// ErrNoPeriod means the signal's level and swing were found but not
+	// its period, so Autoset left the timebase alone.
+	ErrNoPeriod = errors.New("analysis: no periodic signal")
+)
+
+// Signal is what Autoset learns about the input.
+type Signal struct {
+	Min, Max float64
+	// Offset is the DC level, the mean of the samples.
+	Offset float64
+	// Amplitude is half the peak-to-peak swing.
+	Amplitude float64
+	Period    time.Duration
+}
+
+// Estimate measures the swing, DC offset and fundamental period of
+// samples.
+func Estimate(samples []float32, sampleRate int) (Signal, error) {
+	if len(samples) == 0 {
+		return Signal{}, errShortInput
+	}
+
+	sig := Signal{Min: float64(samples[0]), Max: float64(samples[0])}
+	var sum float64
+	for _, v := range samples {
+		sig.Min = math.Min(sig.Min, float64(v))
+		sig.Max = math.Max(sig.Max, float64(v))
+		sum += float64(v)
+	}
+	sig.Offset = sum / float64(len(samples))
+	sig.Amplitude = (sig.Max - sig.Min) / 2
+
+	if sig.Max-sig.Min < autosetMinSwing {
+		return sig, errNoSignal
+	}
+
+	// Search only periods the window can hold twice over
+	d := NewDetector(sampleRate)
+	d.MinFreq = math.Max(d.MinFreq, 2*float64(sampleRate)/float64(len(samples)))
+	if len(samples) < d.Window() {
+		return sig, errShortInput
+	}
+
+	freq, _, ok := d.Detect(samples[len(samples)-d.Window():])
+	if !ok {
+		return sig, ErrNoPeriod
+	}
+	sig.Period = time.Duration(float64(time.Second) / freq)
+
+	return sig, nil
+}
+
+// Autoset analyses the newest ring data and applies the result to acquirer.
+// Without a clear period only the vertical and trigger settings change.
+func Autoset(ring *memory.Ring, acquirer *acquisition.Acquirer) (Signal, error) {
+	bounds, ok := ring.Snapshot()
+	if !ok {
+		return Signal{}, errShortInput
+	}
+
+	// Ring reads end short of the newest sample
+	n := min(acquirer.Stream.Samples(AutosetWindow), bounds.Count()-1)
+	buf := make([]float32, max(n, 0))
+	if err := ring.ReadInto(buf, bounds.Newest-n); err != nil {
+		return Signal{}, fmt.Errorf("analysis: autoset: %w", err)
+	}
+
+	sig, err := Estimate(buf, acquirer.Stream.SampleRate)
+	if err != nil && !errors.Is(err, ErrNoPeriod) {
+		return sig, err
+	}
+
+	// A record may use at most half the ring, so the acquirer can still
+	// find triggers in the rest
+	Apply(sig, acquirer, acquirer.Stream.Duration(ring.Size()/2))
+	return sig, err
+}
+
+// Apply sets the vertical scale, trigger and timebase for sig. Records are
+// capped at maxRecord.
+func Apply(sig Signal, acquirer *acquisition.Acquirer, maxRecord time.Duration) {
+	swing := sig.Max - sig.Min
+	mid := (sig.Max + sig.Min) / 2
+
+	acquirer.SetGain(Gain(swing))
+	acquirer.SetOffset(mid)
+
+	h := autosetHysteresis * swing
+	acquirer.SetTrigger(trigger.Trigger{
+		Polarity: trigger.Positive,
+		Level:    mid,
+		Lower:    mid - h,
+		Upper:    mid + h,
+	})
+
+	if m := acquirer.GetMode(); m == acquisition.ModeRoll || m == acquisition.ModeWaterfall {
+		acquirer.SetMode(acquisition.ModeNormal)
+	}
+
+	if sig.Period <= 0 {
+		return
+	}
+
+	cycles := autosetCycles
+	for cycles > 2 && time.Duration(cycles)*sig.Period > maxRecord {
+		cycles--
+	}
+	length := min(time.Duration(cycles)*sig.Period, maxRecord)
+
+	acquirer.SetRecordLength(length)
+	acquirer.SetHoldOffMode(acquisition.HoldOffTime)
+	acquirer.SetHoldOff(length)
+}
+
+// Gain returns the display gain that fits a peak-to-peak swing within
+// autosetFill of the screen, with the scale per division rounded up to a
+// 1-2-5 step.
+func Gain(swing float64) float64 {
+	const rows = 8
+
+	// Full scale maps to half the screen height at a gain of one
+	perDiv := swing / (autosetFill * rows)
+	exp := math.Floor(math.Log10(perDiv))
+	base := math.Pow(10, exp)
+
+	step := 10 * base
+	for _, s := range gainSteps {
+		if s*base >= perDiv*(1-1e-9) {
+			step = s * base
+			break
+		}
+	}
+	return 2 / (rows * step)
+}
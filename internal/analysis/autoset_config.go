@@ -0,0 +1,24 @@
+package analysis
+
+import "time"
+
+const (
+	// AutosetWindow is how much of the newest ring data Autoset analyses.
+	AutosetWindow = 500 * time.Millisecond
+
+	// autosetCycles is how many periods a record spans after Autoset; the
+	// result is always kept within 2 to 4.
+	autosetCycles = 3
+	// autosetFill is the share of the screen height the signal's
+	// peak-to-peak swing should cover at most.
+	autosetFill = 0.75
+	// autosetHysteresis is the trigger hysteresis as a share of the
+	// peak-to-peak swing on each side of the level.
+	autosetHysteresis = 0.1
+	// autosetMinSwing is the smallest peak-to-peak swing Autoset treats as
+	// a signal rather than silence.
+	autosetMinSwing = 1e-4
+)
+
+// gainSteps are the full-scale units per division Autoset picks from.
+var gainSteps = []float64{1, 2, 5}
@@ -0,0 +1,117 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"oscilloscope/internal/acquisition"
+	"oscilloscope/internal/memory"
+	"oscilloscope/internal/source"
+	"oscilloscope/internal/trigger"
+)
+
+func TestEstimate(t *testing.T) {
+	const freq = 220.0
+	samples := generate(testRate/2, func(s float64) float64 {
+		return 0.2 + 0.3*math.Sin(2*math.Pi*freq*s)
+	})
+
+	sig, err := Estimate(samples, testRate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if math.Abs(sig.Offset-0.2) > 0.001 || math.Abs(sig.Amplitude-0.3) > 0.001 {
+		t.Fatalf("offset %.4f amplitude %.4f, want 0.2 and 0.3", sig.Offset, sig.Amplitude)
+	}
+	want := time.Second / freq
+	if d := sig.Period - want; d.Abs() > want/1000 {
+		t.Fatalf("period %s, want %s", sig.Period, want)
+	}
+}
+
+func TestEstimateSilence(t *testing.T) {
+	if _, err := Estimate(make([]float32, testRate/2), testRate); err != errNoSignal {
+		t.Fatalf("err = %v, want %v", err, errNoSignal)
+	}
+}
+
+func TestGain(t *testing.T) {
+	tests := []struct {
+		swing, perDiv float64
+	}{
+		{1.0, 0.2},   // 1/6 div rounds up to 0.2
+		{0.6, 0.1},   // exactly 0.1
+		{0.05, 0.01}, // 0.0083 rounds up to 0.01
+		{2.0, 0.5},
+	}
+
+	for _, tt := range tests {
+		got := 2 / (8 * Gain(tt.swing))
+		if math.Abs(got-tt.perDiv) > 1e-12 {
+			t.Errorf("swing %g: %g FS/div, want %g", tt.swing, got, tt.perDiv)
+		}
+	}
+}
+
+func TestAutosetLocksOnSignal(t *testing.T) {
+	tests := []struct {
+		name string
+		freq float64
+		wave func(phase float64) float64
+	}{
+		{"sine with offset", 220, func(p float64) float64 { return 0.2 + 0.3*math.Sin(2*math.Pi*p) }},
+		{"square", 55, func(p float64) float64 {
+			if math.Mod(p, 1) < 0.3 {
+				return 0.4
+			}
+			return -0.4
+		}},
+		{"two tones", 110, func(p float64) float64 {
+			return 0.3*math.Sin(2*math.Pi*p) + 0.2*math.Sin(2*math.Pi*3*p+1)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := source.Stream{SampleRate: testRate, BufferSize: source.DefaultBufferSize}
+			ring := memory.New(1 << 16)
+			a := acquisition.New(trigger.New(), stream)
+
+			samples := generate(ring.Size(), func(s float64) float64 { return tt.wave(s * tt.freq) })
+			ring.WriteBatch(0, samples)
+
+			if _, err := Autoset(ring, a); err != nil {
+				t.Fatal(err)
+			}
+
+			period := 1 / tt.freq
+			cycles := a.GetRecordLength().Seconds() / period
+			if cycles < 2-1e-3 || cycles > 4+1e-3 {
+				t.Fatalf("record spans %.2f cycles, want 2 to 4", cycles)
+			}
+
+			// Triggered records must start at the same phase every time
+			a.LastTriggerIndex = -1
+			var phases []float64
+			for newest := a.SamplesPerRecord(); newest < ring.Size() && len(phases) < 3; newest += stream.BufferSize {
+				res := a.Build(ring, newest)
+				if !res.Ready {
+					continue
+				}
+				rec := res.Record
+				at := float64(rec.StartIndex+rec.TriggerIndex) + rec.TriggerOffset
+				phases = append(phases, math.Mod(at/testRate*tt.freq, 1))
+			}
+			if len(phases) < 3 {
+				t.Fatalf("got %d triggered records, want 3", len(phases))
+			}
+			for _, p := range phases[1:] {
+				if d := math.Abs(p - phases[0]); math.Min(d, 1-d) > 0.01 {
+					t.Fatalf("trigger phases %v are not stable", phases)
+				}
+			}
+		})
+	}
+}
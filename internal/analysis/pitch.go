@@ -0,0 +1,136 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+)
+
+// Note is the equal-tempered note nearest a frequency.
+type Note struct {
+	Name   string
+	Octave int
+	// Cents is how far the frequency lies from the note, -50 to +50.
+	Cents float64
+}
+
+func (n Note) String() string {
+	return fmt.Sprintf("%s%d %+.1f cents", n.Name, n.Octave, n.Cents)
+}
+
+// NoteOf returns the note nearest freq with A4 tuned to a4.
+func NoteOf(freq, a4 float64) Note {
+	midi := 69 + 12*math.Log2(freq/a4)
+	nearest := math.Round(midi)
+	n := int(nearest)
+
+	return Note{
+		Name:   noteNames[((n%12)+12)%12],
+		Octave: floorDiv(n, 12) - 1,
+		Cents:  (midi - nearest) * 100,
+	}
+}
+
+// Pitch is one detection.
+type Pitch struct {
+	Frequency float64
+	Note      Note
+	// Clarity is 1 minus the normalised difference at the detected
+	// period, near 1 for a clean periodic signal.
+	Clarity float64
+}
+
+// Detector finds the fundamental frequency with the YIN algorithm.
+type Detector struct {
+	SampleRate int
+	MinFreq    float64
+	MaxFreq    float64
+	Threshold  float64
+
+	diff []float64
+}
+
+func NewDetector(sampleRate int) *Detector {
+	return &Detector{
+		SampleRate: sampleRate,
+		MinFreq:    DefaultMinFreq,
+		MaxFreq:    DefaultMaxFreq,
+		Threshold:  DefaultThreshold,
+	}
+}
+
+// Window is how many samples Detect needs: the longest period searched,
+// compared against itself shifted by up to one more.
+func (d *Detector) Window() int {
+	return 2 * d.maxLag()
+}
+
+func (d *Detector) maxLag() int {
+	return int(math.Ceil(float64(d.SampleRate)/d.MinFreq)) + 1
+}
+
+// Detect returns the fundamental frequency of samples, which should hold
+// at least Window samples. It reports false for signals without a clear
+// period.
+func (d *Detector) Detect(samples []float32) (float64, float64, bool) {
+	maxLag := d.maxLag()
+	minLag := max(int(float64(d.SampleRate)/d.MaxFreq), 2)
+	w := len(samples) - maxLag
+	if w < maxLag || minLag >= maxLag {
+		return 0, 0, false
+	}
+
+	if cap(d.diff) < maxLag+1 {
+		d.diff = make([]float64, maxLag+1)
+	}
+	diff := d.diff[:maxLag+1]
+
+	// Difference function, then cumulative mean normalisation in place
+	diff[0] = 1
+	var running float64
+	for tau := 1; tau <= maxLag; tau++ {
+		var sum float64
+		for j := range w {
+			delta := float64(samples[j]) - float64(samples[j+tau])
+			sum += delta * delta
+		}
+		running += sum
+		if running == 0 {
+			diff[tau] = 1
+		} else {
+			diff[tau] = sum * float64(tau) / running
+		}
+	}
+
+	// The first dip below the threshold, followed down to its minimum,
+	// avoids locking onto a multiple of the period
+	tau := -1
+	for t := minLag; t < maxLag; t++ {
+		if diff[t] < d.Threshold {
+			for t+1 < maxLag && diff[t+1] < diff[t] {
+				t++
+			}
+			tau = t
+			break
+		}
+	}
+	if tau < 0 {
+		return 0, 0, false
+	}
+
+	// Refine between lags with a parabola through the neighbours
+	period := float64(tau)
+	a, b, c := diff[tau-1], diff[tau], diff[tau+1]
+	if den := a - 2*b + c; den != 0 {
+		period += 0.5 * (a - c) / den
+	}
+
+	return float64(d.SampleRate) / period, 1 - b, true
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
@@ -0,0 +1,21 @@
+package analysis
+
+import "time"
+
+const (
+	DefaultA4 = 440.0
+
+	// DefaultMinFreq and DefaultMaxFreq bound the search; the lower bound
+	// sets how many samples each detection needs.
+	DefaultMinFreq = 30.0
+	DefaultMaxFreq = 4200.0
+
+	// DefaultThreshold is the YIN absolute threshold on the cumulative
+	// mean normalised difference.
+	DefaultThreshold = 0.15
+
+	// DefaultTunerInterval is how often the tuner analyses the ring.
+	DefaultTunerInterval = 100 * time.Millisecond
+)
+
+var noteNames = [12]string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
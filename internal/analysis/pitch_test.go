@@ -0,0 +1,128 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"oscilloscope/internal/acquisition"
+	"oscilloscope/internal/memory"
+	"oscilloscope/internal/source"
+	"oscilloscope/internal/trigger"
+)
+
+const testRate = 48000
+
+func generate(n int, f func(t float64) float64) []float32 {
+	s := make([]float32, n)
+	for i := range s {
+		s[i] = float32(f(float64(i) / testRate))
+	}
+	return s
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		freq float64
+		wave func(phase float64) float64
+	}{
+		{"sine", 440, func(p float64) float64 { return 0.5 * math.Sin(2*math.Pi*p) }},
+		{"square", 110, func(p float64) float64 {
+			if math.Mod(p, 1) < 0.5 {
+				return 0.5
+			}
+			return -0.5
+		}},
+		{"saw", 82.41, func(p float64) float64 { return math.Mod(p, 1) - 0.5 }},
+		// A weak fundamental under a strong second harmonic must not be
+		// reported an octave up
+		{"weak fundamental", 196, func(p float64) float64 {
+			return 0.2*math.Sin(2*math.Pi*p) + 0.6*math.Sin(4*math.Pi*p)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDetector(testRate)
+			samples := generate(d.Window(), func(s float64) float64 { return tt.wave(s * tt.freq) })
+
+			got, clarity, ok := d.Detect(samples)
+			if !ok {
+				t.Fatal("no pitch detected")
+			}
+			if math.Abs(got-tt.freq) > 0.001*tt.freq {
+				t.Fatalf("got %.3f Hz, want %.3f", got, tt.freq)
+			}
+			if clarity < 0.9 {
+				t.Fatalf("clarity = %.3f, want close to 1", clarity)
+			}
+		})
+	}
+}
+
+func TestDetectRejectsSilence(t *testing.T) {
+	d := NewDetector(testRate)
+	if _, _, ok := d.Detect(make([]float32, d.Window())); ok {
+		t.Fatal("silence should have no pitch")
+	}
+}
+
+func TestNoteOf(t *testing.T) {
+	tests := []struct {
+		freq, a4 float64
+		name     string
+		octave   int
+		cents    float64
+	}{
+		{440, 440, "A", 4, 0},
+		{261.6256, 440, "C", 4, 0},
+		{445, 440, "A", 4, 1200 * math.Log2(445.0/440)},
+		{27.5, 440, "A", 0, 0},
+		{432, 432, "A", 4, 0},
+		{440, 432, "A", 4, 1200 * math.Log2(440.0/432)},
+		{16.3516, 440, "C", 0, 0},
+		{123.4708, 440, "B", 2, 0},
+	}
+
+	for _, tt := range tests {
+		n := NoteOf(tt.freq, tt.a4)
+		if n.Name != tt.name || n.Octave != tt.octave || math.Abs(n.Cents-tt.cents) > 0.01 {
+			t.Errorf("NoteOf(%g, %g) = %v, want %s%d %+.2f cents", tt.freq, tt.a4, n, tt.name, tt.octave, tt.cents)
+		}
+	}
+}
+
+func TestHoldOffLock(t *testing.T) {
+	stream := source.Stream{SampleRate: testRate, BufferSize: source.DefaultBufferSize}
+	acq := acquisition.New(trigger.New(), stream)
+	acq.SetHoldOffMode(acquisition.HoldOffEvents)
+	acq.SetHoldOff(30 * time.Millisecond)
+	acq.SetRecordLength(200 * time.Millisecond)
+
+	r := NewTunerRunner(memory.New(1<<16), acq, DefaultA4)
+
+	for _, periods := range []int64{1, 2} {
+		if got := r.CycleHoldOffPeriods(); got != int(periods) {
+			t.Fatalf("cycled to %d, want %d", got, periods)
+		}
+		r.lock(periods, 440)
+
+		want := time.Duration(periods) * time.Second / 440
+		if got := acq.GetRecordLength(); (got - want).Abs() > time.Microsecond {
+			t.Fatalf("%d periods: record length %s, want %s", periods, got, want)
+		}
+		if acq.GetHoldOffMode() != acquisition.HoldOffTime || acq.GetHoldOff() >= want {
+			t.Fatalf("%d periods: holdoff %s %s, want time below %s", periods, acq.GetHoldOffMode(), acq.GetHoldOff(), want)
+		}
+	}
+
+	r.CycleHoldOffPeriods()
+	if got := r.CycleHoldOffPeriods(); got != 0 {
+		t.Fatalf("cycled to %d, want off", got)
+	}
+	if acq.GetHoldOffMode() != acquisition.HoldOffEvents || acq.GetHoldOff() != 30*time.Millisecond ||
+		acq.GetRecordLength() != 200*time.Millisecond {
+		t.Fatalf("timebase not restored: %s %s %s", acq.GetHoldOffMode(), acq.GetHoldOff(), acq.GetRecordLength())
+	}
+}
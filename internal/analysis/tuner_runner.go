@@ -0,0 +1,145 @@
+package analysis
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"oscilloscope/internal/acquisition"
+	"oscilloscope/internal/memory"
+)
+
+// TunerRunner detects the pitch of the newest ring data at a fixed
+// interval. When HoldOffPeriods is non-zero it also sets the acquirer's
+// time holdoff and record length so each record shows exactly that many
+// periods; turning the lock off restores the timebase it replaced.
+type TunerRunner struct {
+	Ring     *memory.Ring
+	Detector *Detector
+	Acquirer *acquisition.Acquirer
+	A4       float64
+	Interval time.Duration
+
+	HoldOffPeriods atomic.Int64
+
+	latest atomic.Pointer[Pitch]
+	buf    []float32
+
+	mu    sync.Mutex // serialises the lock with CycleHoldOffPeriods
+	saved timebase
+}
+
+// timebase is the acquirer state the holdoff lock overrides.
+type timebase struct {
+	holdOffMode  acquisition.HoldOffMode
+	holdOff      time.Duration
+	recordLength time.Duration
+}
+
+func NewTunerRunner(ring *memory.Ring, acquirer *acquisition.Acquirer, a4 float64) *TunerRunner {
+	return &TunerRunner{
+		Ring:     ring,
+		Detector: NewDetector(acquirer.Stream.SampleRate),
+		Acquirer: acquirer,
+		A4:       a4,
+		Interval: DefaultTunerInterval,
+	}
+}
+
+// Latest returns the most recent detection, or false while the input has
+// no clear pitch.
+func (r *TunerRunner) Latest() (Pitch, bool) {
+	p := r.latest.Load()
+	if p == nil {
+		return Pitch{}, false
+	}
+	return *p, true
+}
+
+func (r *TunerRunner) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			r.detect()
+		}
+	}
+}
+
+func (r *TunerRunner) detect() {
+	n := r.Detector.Window()
+	if cap(r.buf) < n {
+		r.buf = make([]float32, n)
+	}
+	buf := r.buf[:n]
+
+	bounds, ok := r.Ring.Snapshot()
+	if !ok || bounds.Count() <= n {
+		r.latest.Store(nil)
+		return
+	}
+	if err := r.Ring.ReadInto(buf, bounds.Newest-n); err != nil {
+		return
+	}
+
+	freq, clarity, ok := r.Detector.Detect(buf)
+	if !ok {
+		r.latest.Store(nil)
+		return
+	}
+
+	r.latest.Store(&Pitch{Frequency: freq, Note: NoteOf(freq, r.A4), Clarity: clarity})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if periods := r.HoldOffPeriods.Load(); periods > 0 {
+		r.lock(periods, freq)
+	}
+}
+
+// lock fits the holdoff and record length to periods cycles of freq.
+func (r *TunerRunner) lock(periods int64, freq float64) {
+	period := time.Duration(float64(time.Second) / freq)
+
+	// Half a period short of N, so jitter cannot push the next crossing
+	// inside the holdoff
+	r.Acquirer.SetHoldOffMode(acquisition.HoldOffTime)
+	r.Acquirer.SetHoldOff(time.Duration(periods)*period - period/2)
+
+	// A record may use at most half the ring, as with Autoset
+	maxRecord := r.Acquirer.Stream.Duration(r.Ring.Size() / 2)
+	r.Acquirer.SetRecordLength(min(time.Duration(periods)*period, maxRecord))
+}
+
+// CycleHoldOffPeriods steps the holdoff lock through off, 1, 2 and 4
+// periods. Turning it on remembers the acquirer's timebase and turning it
+// off restores it.
+func (r *TunerRunner) CycleHoldOffPeriods() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	prev := r.HoldOffPeriods.Load()
+	next := map[int64]int64{0: 1, 1: 2, 2: 4}[prev]
+
+	switch {
+	case prev == 0 && next > 0:
+		r.saved = timebase{
+			holdOffMode:  r.Acquirer.GetHoldOffMode(),
+			holdOff:      r.Acquirer.GetHoldOff(),
+			recordLength: r.Acquirer.GetRecordLength(),
+		}
+	case prev > 0 && next == 0:
+		r.Acquirer.SetHoldOffMode(r.saved.holdOffMode)
+		r.Acquirer.SetHoldOff(r.saved.holdOff)
+		r.Acquirer.SetRecordLength(r.saved.recordLength)
+	}
+
+	r.HoldOffPeriods.Store(next)
+	return int(next)
+}
@@ -1,52 +1,95 @@
 package audio
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
-	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gordonklaus/portaudio"
+	"oscilloscope/internal/filter"
 	"oscilloscope/internal/memory"
+	"oscilloscope/internal/source"
 )
 
+// stallTimeout is how long the input may go without a callback before the
+// stream is considered dead.
+const stallTimeout = time.Second
+
 type PortAudioRunner struct {
-	Ring *memory.Ring
-	Cond *sync.Cond
+	Rings    []*memory.Ring // one per input channel, ch1 first
+	Notifier *memory.Notifier
+	Stream   source.Stream
+	Filter   *filter.Stage // filters ch1; nil leaves it unfiltered
 
 	stream  *portaudio.Stream
 	index   int
-	convBuf []float32 // pre-allocated float32→float32 conversion buffer
+	convBuf []float32 // pre-allocated buffer for deinterleaving one channel
+
+	lastCallback atomic.Int64 // unix nanoseconds
+}
+
+// Negotiate checks the requested stream against the input device and falls
+// back to the device's default sample rate if that is one we support.
+func Negotiate(requested source.Stream) (source.Stream, error) {
+	device, err := findBlackHoleDevice()
+	if err != nil {
+		return source.Stream{}, err
+	}
+	if device.MaxInputChannels < requested.Channels {
+		return source.Stream{}, fmt.Errorf("%s has %d input channel(s), want %d", device.Name, device.MaxInputChannels, requested.Channels)
+	}
+
+	candidates := []source.Stream{requested}
+
+	fallback := requested
+	fallback.SampleRate = int(device.DefaultSampleRate)
+	if fallback != requested && fallback.Validate() == nil {
+		candidates = append(candidates, fallback)
+	}
+
+	var errs []error
+	for _, s := range candidates {
+		err := portaudio.IsFormatSupported(streamParameters(device, s), func(in []float32) {})
+		if err == nil {
+			return s, nil
+		}
+		errs = append(errs, fmt.Errorf("%d Hz: %w", s.SampleRate, err))
+	}
+
+	return source.Stream{}, fmt.Errorf("%s supports none of the requested formats: %w", device.Name, errors.Join(errs...))
 }
 
+// NewPortAudioRunner opens the input stream as described by stream, which
+// should come from Negotiate, writing each channel to its own ring. Every
+// block of ch1 passes through stage, if any, before it reaches its ring.
 func NewPortAudioRunner(
-	ring *memory.Ring,
-	cond *sync.Cond,
-	sampleRate float64,
-	bufferSize int,
+	rings []*memory.Ring,
+	notifier *memory.Notifier,
+	stream source.Stream,
+	stage *filter.Stage,
 ) (*PortAudioRunner, error) {
+	if len(rings) != stream.Channels {
+		return nil, fmt.Errorf("audio: %d rings for %d channels", len(rings), stream.Channels)
+	}
+
 	device, err := findBlackHoleDevice()
 	if err != nil {
 		return nil, err
 	}
 
 	runner := &PortAudioRunner{
-		Ring:    ring,
-		Cond:    cond,
-		convBuf: make([]float32, bufferSize),
-	}
-
-	params := portaudio.StreamParameters{
-		Input: portaudio.StreamDeviceParameters{
-			Device:   device,
-			Channels: 1,
-			Latency:  device.DefaultLowInputLatency,
-		},
-		SampleRate:      sampleRate,
-		FramesPerBuffer: bufferSize,
+		Rings:    rings,
+		Notifier: notifier,
+		Stream:   stream,
+		Filter:   stage,
+		convBuf:  make([]float32, stream.BufferSize),
 	}
 
-	stream, err := portaudio.OpenStream(
-		params,
+	pa, err := portaudio.OpenStream(
+		streamParameters(device, stream),
 		func(in []float32) {
 			runner.process(in)
 		},
@@ -55,10 +98,22 @@ func NewPortAudioRunner(
 		return nil, err
 	}
 
-	runner.stream = stream
+	runner.stream = pa
 	return runner, nil
 }
 
+func streamParameters(device *portaudio.DeviceInfo, s source.Stream) portaudio.StreamParameters {
+	return portaudio.StreamParameters{
+		Input: portaudio.StreamDeviceParameters{
+			Device:   device,
+			Channels: s.Channels,
+			Latency:  device.DefaultLowInputLatency,
+		},
+		SampleRate:      float64(s.SampleRate),
+		FramesPerBuffer: s.BufferSize,
+	}
+}
+
 func findBlackHoleDevice() (*portaudio.DeviceInfo, error) {
 	devices, err := portaudio.Devices()
 	if err != nil {
@@ -75,20 +130,60 @@ func findBlackHoleDevice() (*portaudio.DeviceInfo, error) {
 }
 
 func (r *PortAudioRunner) process(in []float32) {
-	// Convert float64→float32 into pre-allocated buffer
-	buf := r.convBuf[:len(in)]
-	for i, v := range in {
-		buf[i] = float32(v)
+	channels := len(r.Rings)
+	frames := len(in) / channels
+	buf := r.convBuf[:frames]
+
+	// Write ch1 last: readers take their bounds from it, so any range it
+	// holds is already in every other channel's ring
+	for ch := channels - 1; ch >= 0; ch-- {
+		for i := range buf {
+			buf[i] = in[i*channels+ch]
+		}
+
+		// Filter the continuous stream so its state carries across records
+		if ch == 0 && r.Filter != nil {
+			r.Filter.Process(buf)
+		}
+		r.Rings[ch].WriteBatch(r.index, buf)
 	}
+	r.index += frames
+
+	// Tell the acquirer how far the written data now reaches
+	r.Notifier.Publish(r.index - 1)
+
+	r.lastCallback.Store(time.Now().UnixNano())
+}
 
-	// Single lock acquisition for all samples
-	r.Ring.WriteBatch(r.index, buf)
-	r.index += len(in)
+// Run starts the stream and keeps it running until ctx is cancelled. It
+// returns an error if the stream fails to start or stop, or if the input
+// stops delivering callbacks.
+func (r *PortAudioRunner) Run(ctx context.Context) (err error) {
+	r.lastCallback.Store(time.Now().UnixNano())
 
-	// Signal the acquirer that new data is available
-	r.Cond.L.Lock()
-	r.Cond.Broadcast()
-	r.Cond.L.Unlock()
+	if err := r.Start(); err != nil {
+		return fmt.Errorf("audio: start stream: %w", err)
+	}
+	defer func() {
+		if stopErr := r.Stop(); stopErr != nil && err == nil {
+			err = fmt.Errorf("audio: stop stream: %w", stopErr)
+		}
+	}()
+
+	ticker := time.NewTicker(stallTimeout / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			last := time.Unix(0, r.lastCallback.Load())
+			if stalled := now.Sub(last); stalled > stallTimeout {
+				return fmt.Errorf("audio: no input for %s", stalled.Round(time.Millisecond))
+			}
+		}
+	}
 }
 
 func (r *PortAudioRunner) Start() error { return r.stream.Start() }
@@ -0,0 +1,160 @@
+package filter
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// Settings describes the filter stage. Cutoff is the corner or centre
+// frequency in Hz, and GainDB only applies to the shelving kinds.
+type Settings struct {
+	Kind    Kind
+	Cutoff  float64
+	Q       float64
+	GainDB  float64
+	DCBlock bool
+}
+
+func DefaultSettings() Settings {
+	return Settings{
+		Kind:   Off,
+		Cutoff: DefaultCutoff,
+		Q:      DefaultQ,
+		GainDB: DefaultGainDB,
+	}
+}
+
+// Coefficients of a biquad normalised so that a0 is 1.
+type Coefficients struct {
+	B0, B1, B2 float64
+	A1, A2     float64
+}
+
+// Passthrough leaves the signal unchanged.
+var Passthrough = Coefficients{B0: 1}
+
+// Design returns the biquad for s at sampleRate, following the RBJ audio
+// EQ cookbook. Off yields Passthrough.
+func Design(s Settings, sampleRate int) Coefficients {
+	if s.Kind == Off || sampleRate <= 0 {
+		return Passthrough
+	}
+
+	fs := float64(sampleRate)
+	f := math.Min(math.Max(s.Cutoff, 1), maxCutoffRatio*fs)
+	q := s.Q
+	if q <= 0 {
+		q = DefaultQ
+	}
+
+	w0 := 2 * math.Pi * f / fs
+	cos, sin := math.Cos(w0), math.Sin(w0)
+	alpha := sin / (2 * q)
+	a := math.Pow(10, s.GainDB/40)
+	sa := 2 * math.Sqrt(a) * alpha
+
+	var b0, b1, b2, a0, a1, a2 float64
+	switch s.Kind {
+	case LowPass:
+		b0, b1, b2 = (1-cos)/2, 1-cos, (1-cos)/2
+		a0, a1, a2 = 1+alpha, -2*cos, 1-alpha
+	case HighPass:
+		b0, b1, b2 = (1+cos)/2, -(1 + cos), (1+cos)/2
+		a0, a1, a2 = 1+alpha, -2*cos, 1-alpha
+	case BandPass:
+		b0, b1, b2 = alpha, 0, -alpha
+		a0, a1, a2 = 1+alpha, -2*cos, 1-alpha
+	case Notch:
+		b0, b1, b2 = 1, -2*cos, 1
+		a0, a1, a2 = 1+alpha, -2*cos, 1-alpha
+	case LowShelf:
+		b0 = a * ((a + 1) - (a-1)*cos + sa)
+		b1 = 2 * a * ((a - 1) - (a+1)*cos)
+		b2 = a * ((a + 1) - (a-1)*cos - sa)
+		a0 = (a + 1) + (a-1)*cos + sa
+		a1 = -2 * ((a - 1) + (a+1)*cos)
+		a2 = (a + 1) + (a-1)*cos - sa
+	case HighShelf:
+		b0 = a * ((a + 1) + (a-1)*cos + sa)
+		b1 = -2 * a * ((a - 1) + (a+1)*cos)
+		b2 = a * ((a + 1) + (a-1)*cos - sa)
+		a0 = (a + 1) - (a-1)*cos + sa
+		a1 = 2 * ((a - 1) - (a+1)*cos)
+		a2 = (a + 1) - (a-1)*cos - sa
+	default:
+		return Passthrough
+	}
+
+	return Coefficients{B0: b0 / a0, B1: b1 / a0, B2: b2 / a0, A1: a1 / a0, A2: a2 / a0}
+}
+
+// DCBlocker returns a one-pole high-pass with its corner at DCCutoff,
+// scaled to unity gain at Nyquist.
+func DCBlocker(sampleRate int) Coefficients {
+	if sampleRate <= 0 {
+		return Passthrough
+	}
+	r := math.Exp(-2 * math.Pi * DCCutoff / float64(sampleRate))
+	g := (1 + r) / 2
+	return Coefficients{B0: g, B1: -g, A1: -r}
+}
+
+// Response returns the gain of c at freq Hz.
+func (c Coefficients) Response(freq float64, sampleRate int) float64 {
+	z := cmplx.Exp(complex(0, -2*math.Pi*freq/float64(sampleRate)))
+	num := complex(c.B0, 0) + complex(c.B1, 0)*z + complex(c.B2, 0)*z*z
+	den := 1 + complex(c.A1, 0)*z + complex(c.A2, 0)*z*z
+	return cmplx.Abs(num / den)
+}
+
+// section is one biquad in transposed direct form II. Its coefficients
+// move linearly towards target over a ramp so that changes do not click,
+// and its state carries over between calls.
+type section struct {
+	cur, target, step Coefficients
+	ramp              int
+	z1, z2            float64
+}
+
+func (s *section) retarget(c Coefficients, samples int) {
+	s.target = c
+	if samples <= 0 {
+		s.cur, s.ramp = c, 0
+		return
+	}
+
+	n := float64(samples)
+	s.step = Coefficients{
+		B0: (c.B0 - s.cur.B0) / n,
+		B1: (c.B1 - s.cur.B1) / n,
+		B2: (c.B2 - s.cur.B2) / n,
+		A1: (c.A1 - s.cur.A1) / n,
+		A2: (c.A2 - s.cur.A2) / n,
+	}
+	s.ramp = samples
+}
+
+func (s *section) process(x float64) float64 {
+	if s.ramp > 0 {
+		s.ramp--
+		if s.ramp == 0 {
+			s.cur = s.target
+		} else {
+			s.cur.B0 += s.step.B0
+			s.cur.B1 += s.step.B1
+			s.cur.B2 += s.step.B2
+			s.cur.A1 += s.step.A1
+			s.cur.A2 += s.step.A2
+		}
+	}
+
+	c := s.cur
+	y := c.B0*x + s.z1
+	s.z1 = c.B1*x - c.A1*y + s.z2
+	s.z2 = c.B2*x - c.A2*y
+	return y
+}
+
+func (s *section) idle() bool {
+	return s.ramp == 0 && s.cur == Passthrough && s.z1 == 0 && s.z2 == 0
+}
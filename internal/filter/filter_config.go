@@ -0,0 +1,66 @@
+package filter
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	DefaultCutoff = 1000.0
+	DefaultQ      = 0.7071
+	DefaultGainDB = 6.0
+
+	// DCCutoff is the -3 dB corner of the DC blocker in Hz, low enough to
+	// leave audio untouched.
+	DCCutoff = 5.0
+
+	// rampTime is how long a parameter change takes to reach the new
+	// coefficients, short enough to feel immediate but long enough that
+	// the output does not step.
+	rampTime = 20 * time.Millisecond
+
+	// maxCutoffRatio keeps the corner frequency below Nyquist, where the
+	// bilinear designs degenerate.
+	maxCutoffRatio = 0.49
+)
+
+// QSteps are the resonances the display cycles through.
+var QSteps = []float64{0.5, DefaultQ, 1, 2, 5, 10}
+
+type Kind int32
+
+const (
+	Off Kind = iota
+	LowPass
+	HighPass
+	// BandPass has 0 dB gain at the centre frequency.
+	BandPass
+	Notch
+	LowShelf
+	HighShelf
+	kindCount
+)
+
+var kindNames = [kindCount]string{"off", "lowpass", "highpass", "bandpass", "notch", "lowshelf", "highshelf"}
+
+func (k Kind) String() string {
+	if k < 0 || k >= kindCount {
+		return kindNames[Off]
+	}
+	return kindNames[k]
+}
+
+// Next returns the kind after k, wrapping around.
+func (k Kind) Next() Kind {
+	return (k + 1) % kindCount
+}
+
+// ParseKind returns the kind named s, as printed by String.
+func ParseKind(s string) (Kind, error) {
+	for k, name := range kindNames {
+		if name == s {
+			return Kind(k), nil
+		}
+	}
+	return Off, fmt.Errorf("filter: unknown kind %q, want one of %v", s, kindNames)
+}
@@ -0,0 +1,146 @@
+package filter
+
+import (
+	"math"
+	"testing"
+)
+
+const testRate = 48000
+
+func tone(n int, freq, amp, dc float64) []float32 {
+	s := make([]float32, n)
+	for i := range s {
+		s[i] = float32(dc + amp*math.Sin(2*math.Pi*freq*float64(i)/testRate))
+	}
+	return s
+}
+
+func peak(s []float32) float64 {
+	var p float64
+	for _, v := range s {
+		p = math.Max(p, math.Abs(float64(v)))
+	}
+	return p
+}
+
+func TestDesignResponse(t *testing.T) {
+	const fc = 1000.0
+	shelf := math.Pow(10, DefaultGainDB/20)
+
+	tests := []struct {
+		kind Kind
+		freq float64
+		want float64
+	}{
+		{LowPass, 10, 1},
+		{LowPass, fc, DefaultQ},
+		{LowPass, 20000, 0},
+		{HighPass, 20000, 1},
+		{HighPass, fc, DefaultQ},
+		{HighPass, 10, 0},
+		{BandPass, fc, 1},
+		{Notch, fc, 0},
+		{Notch, 20000, 1},
+		{LowShelf, 10, shelf},
+		{LowShelf, 20000, 1},
+		{HighShelf, 20000, shelf},
+		{HighShelf, 10, 1},
+		{Off, fc, 1},
+	}
+
+	for _, tt := range tests {
+		s := DefaultSettings()
+		s.Kind, s.Cutoff = tt.kind, fc
+		got := Design(s, testRate).Response(tt.freq, testRate)
+		if math.Abs(got-tt.want) > 0.02 {
+			t.Errorf("%s at %g Hz: gain %.4f, want %.4f", tt.kind, tt.freq, got, tt.want)
+		}
+	}
+}
+
+func TestDCBlocker(t *testing.T) {
+	st := NewStage(testRate, Settings{DCBlock: true})
+
+	buf := tone(testRate, 1000, 0.5, 0.3)
+	st.Process(buf)
+
+	tail := buf[len(buf)/2:]
+	var mean float64
+	for _, v := range tail {
+		mean += float64(v)
+	}
+	mean /= float64(len(tail))
+
+	if math.Abs(mean) > 1e-3 {
+		t.Errorf("mean after DC block = %g, want 0", mean)
+	}
+	if p := peak(tail); math.Abs(p-0.5) > 0.01 {
+		t.Errorf("tone peak = %g, want 0.5", p)
+	}
+}
+
+// Filtering a stream block by block must give the same output as filtering
+// it in one go, since records are cut from the continuous stream.
+func TestStateCarriesAcrossBlocks(t *testing.T) {
+	s := DefaultSettings()
+	s.Kind, s.DCBlock = LowPass, true
+
+	in := tone(4096, 3000, 0.8, 0.1)
+	whole := append([]float32(nil), in...)
+	NewStage(testRate, s).Process(whole)
+
+	st := NewStage(testRate, s)
+	blocks := append([]float32(nil), in...)
+	for i := 0; i < len(blocks); i += 100 {
+		st.Process(blocks[i:min(i+100, len(blocks))])
+	}
+
+	for i := range whole {
+		if whole[i] != blocks[i] {
+			t.Fatalf("sample %d = %g in blocks, %g whole", i, blocks[i], whole[i])
+		}
+	}
+}
+
+// A change of settings mid-stream ramps in rather than stepping the output.
+func TestLiveChangeIsSmooth(t *testing.T) {
+	const freq = 200.0
+	s := DefaultSettings()
+	st := NewStage(testRate, s)
+
+	in := tone(testRate/2, freq, 0.5, 0)
+	out := append([]float32(nil), in...)
+
+	half := len(out) / 2
+	st.Process(out[:half])
+	s.Kind, s.Cutoff = LowShelf, 1000
+	st.Set(s)
+	st.Process(out[half:])
+
+	// A 200 Hz sine at 0.5 moves at most 2π·200·0.5/48000 per sample; with
+	// the shelf's +6 dB the output may move twice that, and a click would
+	// move far more.
+	limit := 2 * 2 * math.Pi * freq * 0.5 / testRate * 1.1
+	for i := 1; i < len(out); i++ {
+		if d := math.Abs(float64(out[i] - out[i-1])); d > limit {
+			t.Fatalf("step of %g at sample %d, limit %g", d, i, limit)
+		}
+	}
+
+	shelf := math.Pow(10, DefaultGainDB/20)
+	if p := peak(out[len(out)-testRate/freq:]); math.Abs(p-0.5*shelf) > 0.02 {
+		t.Errorf("settled peak = %g, want %g", p, 0.5*shelf)
+	}
+}
+
+func TestParseKind(t *testing.T) {
+	for k := range kindCount {
+		got, err := ParseKind(k.String())
+		if err != nil || got != k {
+			t.Errorf("ParseKind(%q) = %v, %v", k, got, err)
+		}
+	}
+	if _, err := ParseKind("bogus"); err == nil {
+		t.Error("ParseKind accepted an unknown kind")
+	}
+}
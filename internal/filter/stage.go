@@ -0,0 +1,61 @@
+package filter
+
+import "sync/atomic"
+
+// Stage filters the continuous input stream in place: a DC blocker
+// followed by one biquad. Settings may be changed from any goroutine; the
+// audio callback picks them up on its next block and ramps to them.
+type Stage struct {
+	SampleRate int
+
+	settings atomic.Pointer[Settings]
+	applied  *Settings
+
+	dc     section
+	biquad section
+}
+
+func NewStage(sampleRate int, s Settings) *Stage {
+	st := &Stage{
+		SampleRate: sampleRate,
+		dc:         section{cur: Passthrough, target: Passthrough},
+		biquad:     section{cur: Passthrough, target: Passthrough},
+	}
+	st.settings.Store(&s)
+	st.apply(&s, 0)
+	return st
+}
+
+func (st *Stage) Set(s Settings) { st.settings.Store(&s) }
+
+func (st *Stage) Get() Settings { return *st.settings.Load() }
+
+// Process filters buf in place. It must only be called from one goroutine.
+func (st *Stage) Process(buf []float32) {
+	if s := st.settings.Load(); s != st.applied {
+		st.apply(s, int(rampTime.Seconds()*float64(st.SampleRate)))
+	}
+	if st.dc.idle() && st.biquad.idle() {
+		return
+	}
+
+	for i, v := range buf {
+		x := st.dc.process(float64(v))
+		buf[i] = float32(st.biquad.process(x))
+	}
+}
+
+func (st *Stage) apply(s *Settings, ramp int) {
+	st.applied = s
+
+	dc := Passthrough
+	if s.DCBlock {
+		dc = DCBlocker(st.SampleRate)
+	}
+	if dc != st.dc.target {
+		st.dc.retarget(dc, ramp)
+	}
+	if c := Design(*s, st.SampleRate); c != st.biquad.target {
+		st.biquad.retarget(c, ramp)
+	}
+}
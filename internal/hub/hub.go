@@ -0,0 +1,216 @@
+package hub
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"oscilloscope/internal/record"
+)
+
+type Policy int
+
+const (
+	// LatestOnly keeps the newest Depth records, dropping the oldest.
+	LatestOnly Policy = iota
+	// BoundedQueue keeps the first Depth records, dropping new ones while
+	// the queue is full.
+	BoundedQueue
+	// Block never drops. A backlog is kept per subscriber and only that
+	// subscriber's delivery waits on its reader. The backlog is unbounded,
+	// so a reader that stalls for good grows memory without limit; watch
+	// Backlog for subscribers that might.
+	Block
+)
+
+// Hub fans records out to any number of subscribers. Publish never waits on
+// a subscriber, so one slow consumer cannot hold up the others.
+type Hub struct {
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+type Subscription struct {
+	Name   string
+	Policy Policy
+
+	hub     *Hub
+	out     chan record.Record
+	dropped atomic.Uint64
+
+	// Block policy backlog, drained by pump.
+	mu      sync.Mutex
+	backlog []record.Record
+	wake    chan struct{}
+	quit    chan struct{}
+	done    chan struct{}
+}
+
+func New() *Hub {
+	return &Hub{subs: make(map[*Subscription]struct{})}
+}
+
+// Subscribe registers a consumer with its own buffer depth and drop policy.
+// Subscribing to a closed hub returns a subscription whose channel is
+// already closed.
+func (h *Hub) Subscribe(name string, depth int, policy Policy) *Subscription {
+	s := &Subscription{
+		Name:   name,
+		Policy: policy,
+		hub:    h,
+		out:    make(chan record.Record, max(depth, 1)),
+	}
+
+	if policy == Block {
+		s.wake = make(chan struct{}, 1)
+		s.quit = make(chan struct{})
+		s.done = make(chan struct{})
+		go s.pump()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		s.close()
+		return s
+	}
+
+	h.subs[s] = struct{}{}
+	return s
+}
+
+func (h *Hub) Publish(rec record.Record) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+
+	for s := range h.subs {
+		s.offer(rec)
+	}
+}
+
+// Close closes every subscriber channel. Records still in a Block backlog
+// are discarded.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+	h.closed = true
+
+	for s := range h.subs {
+		s.close()
+		delete(h.subs, s)
+	}
+}
+
+func (h *Hub) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return len(h.subs)
+}
+
+func (s *Subscription) C() <-chan record.Record { return s.out }
+
+// Dropped returns how many records this subscriber has lost.
+func (s *Subscription) Dropped() uint64 { return s.dropped.Load() }
+
+// Backlog returns how many records are waiting for a Block subscriber
+// beyond those already being delivered.
+func (s *Subscription) Backlog() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.backlog)
+}
+
+// Close unsubscribes and closes the channel.
+func (s *Subscription) Close() {
+	h := s.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subs[s]; !ok {
+		return
+	}
+	delete(h.subs, s)
+	s.close()
+}
+
+// offer is only called with the hub lock held, so the hub is the sole
+// sender on out.
+func (s *Subscription) offer(rec record.Record) {
+	switch s.Policy {
+	case Block:
+		s.mu.Lock()
+		s.backlog = append(s.backlog, rec)
+		s.mu.Unlock()
+
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+
+	case BoundedQueue:
+		select {
+		case s.out <- rec:
+		default:
+			s.dropped.Add(1)
+		}
+
+	default:
+		for {
+			select {
+			case s.out <- rec:
+				return
+			default:
+			}
+
+			select {
+			case <-s.out:
+				s.dropped.Add(1)
+			default:
+			}
+		}
+	}
+}
+
+func (s *Subscription) pump() {
+	defer close(s.done)
+
+	for {
+		s.mu.Lock()
+		pending := s.backlog
+		s.backlog = nil
+		s.mu.Unlock()
+
+		for _, rec := range pending {
+			select {
+			case s.out <- rec:
+			case <-s.quit:
+				return
+			}
+		}
+
+		select {
+		case <-s.wake:
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+func (s *Subscription) close() {
+	if s.Policy == Block {
+		close(s.quit)
+		<-s.done
+	}
+	close(s.out)
+}
@@ -0,0 +1,93 @@
+package hub
+
+import (
+	"testing"
+	"time"
+
+	"oscilloscope/internal/record"
+)
+
+func publishN(h *Hub, n int) {
+	for i := range n {
+		h.Publish(record.Record{Sequence: uint64(i + 1)})
+	}
+}
+
+func TestLatestOnlyKeepsNewest(t *testing.T) {
+	h := New()
+	s := h.Subscribe("display", 1, LatestOnly)
+
+	publishN(h, 5)
+
+	rec := <-s.C()
+	if rec.Sequence != 5 {
+		t.Fatalf("got sequence %d, want 5", rec.Sequence)
+	}
+	if s.Dropped() != 4 {
+		t.Fatalf("dropped = %d, want 4", s.Dropped())
+	}
+}
+
+func TestBoundedQueueKeepsOldest(t *testing.T) {
+	h := New()
+	s := h.Subscribe("export", 2, BoundedQueue)
+
+	publishN(h, 5)
+
+	if a, b := <-s.C(), <-s.C(); a.Sequence != 1 || b.Sequence != 2 {
+		t.Fatalf("got sequences %d, %d, want 1, 2", a.Sequence, b.Sequence)
+	}
+	if s.Dropped() != 3 {
+		t.Fatalf("dropped = %d, want 3", s.Dropped())
+	}
+}
+
+func TestBlockIsLosslessAndIsolated(t *testing.T) {
+	h := New()
+	slow := h.Subscribe("slow", 1, Block)
+	fast := h.Subscribe("fast", 1, LatestOnly)
+
+	finished := make(chan struct{})
+	go func() {
+		publishN(h, 100)
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("publish waited on a slow subscriber")
+	}
+
+	if rec := <-fast.C(); rec.Sequence != 100 {
+		t.Fatalf("fast subscriber got %d, want 100", rec.Sequence)
+	}
+
+	for want := uint64(1); want <= 100; want++ {
+		if rec := <-slow.C(); rec.Sequence != want {
+			t.Fatalf("slow subscriber got %d, want %d", rec.Sequence, want)
+		}
+	}
+	if slow.Dropped() != 0 {
+		t.Fatalf("block subscriber dropped %d", slow.Dropped())
+	}
+}
+
+func TestCloseClosesSubscribers(t *testing.T) {
+	h := New()
+	a := h.Subscribe("a", 1, LatestOnly)
+	b := h.Subscribe("b", 1, Block)
+
+	b.Close()
+	if h.Len() != 1 {
+		t.Fatalf("hub has %d subscribers, want 1", h.Len())
+	}
+
+	h.Close()
+	if _, ok := <-a.C(); ok {
+		t.Fatal("channel still open after Close")
+	}
+	if _, ok := <-b.C(); ok {
+		t.Fatal("unsubscribed channel still open")
+	}
+}
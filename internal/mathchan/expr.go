@@ -0,0 +1,292 @@
+package mathchan
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+
+	"oscilloscope/internal/record"
+)
+
+var errMismatch = errors.New("mathchan: input records differ in length or sample rate")
+
+// Expr is a parsed expression, a graph of nodes evaluated over whole
+// records.
+type Expr struct {
+	src      string
+	root     node
+	channels []int
+}
+
+func (e *Expr) String() string { return e.src }
+
+// Channels lists the channels the expression reads, in ascending order.
+func (e *Expr) Channels() []int { return e.channels }
+
+// Check reports whether the expression can be evaluated on an input with
+// the given number of channels.
+func (e *Expr) Check(channels int) error {
+	if len(e.channels) == 0 {
+		return fmt.Errorf("mathchan: %q reads no channel", e.src)
+	}
+	if last := e.channels[len(e.channels)-1]; last >= channels {
+		return fmt.Errorf("mathchan: %q reads ch%d but the input has %d channel(s)", e.src, last+1, channels)
+	}
+	return nil
+}
+
+// Eval computes the expression over the records of every channel it
+// reads. The result takes its metadata from the lowest channel read, so it
+// shares that channel's timing and trigger.
+func (e *Expr) Eval(inputs map[int]record.Record) (record.Record, error) {
+	return e.eval(inputs, nil)
+}
+
+// Inputs keys rec and its siblings by channel, as Eval takes them.
+func Inputs(rec record.Record) map[int]record.Record {
+	inputs := make(map[int]record.Record, 1+len(rec.Siblings))
+	inputs[rec.Channel] = rec
+	for _, sib := range rec.Siblings {
+		inputs[sib.Channel] = sib
+	}
+	return inputs
+}
+
+func (e *Expr) eval(inputs map[int]record.Record, carries map[node]*carry) (record.Record, error) {
+	env := env{inputs: inputs, length: -1, carries: carries}
+
+	for _, ch := range e.channels {
+		rec, ok := inputs[ch]
+		if !ok {
+			return record.Record{}, fmt.Errorf("mathchan: no record for ch%d", ch+1)
+		}
+		if env.length < 0 {
+			env.length = len(rec.Samples)
+			env.rate = rec.SampleRate
+		} else if len(rec.Samples) != env.length || rec.SampleRate != env.rate {
+			return record.Record{}, errMismatch
+		}
+	}
+	if env.length < 0 {
+		return record.Record{}, fmt.Errorf("mathchan: %q reads no channel", e.src)
+	}
+
+	values := e.root.eval(env)
+
+	out := inputs[e.channels[0]]
+	out.Samples = make([]float32, len(values))
+	for i, v := range values {
+		out.Samples[i] = float32(v)
+	}
+	out.Min, out.Max, out.EnvelopeMin, out.EnvelopeMax = nil, nil, nil, nil
+	return out, nil
+}
+
+type env struct {
+	inputs map[int]record.Record
+	length int
+	rate   int
+
+	carries map[node]*carry // nil outside a Stream
+}
+
+// carry is the state a node keeps from one block of a stream to the next.
+type carry struct {
+	started bool
+	prev    float64
+	sum     float64
+}
+
+// carried returns n's state in a stream, or nil when evaluating a record.
+func (env env) carried(n node) *carry {
+	if env.carries == nil {
+		return nil
+	}
+	c, ok := env.carries[n]
+	if !ok {
+		c = &carry{}
+		env.carries[n] = c
+	}
+	return c
+}
+
+// Stream evaluates an expression over consecutive blocks of a continuous
+// signal, such as the samples roll mode takes from the ring each tick.
+// integ keeps integrating across blocks, and diff uses backward
+// differences so that it needs no sample from the next block.
+type Stream struct {
+	expr    *Expr
+	carries map[node]*carry
+}
+
+func (e *Expr) NewStream() *Stream {
+	return &Stream{expr: e, carries: make(map[node]*carry)}
+}
+
+// Process evaluates the next block of every channel the expression reads.
+func (s *Stream) Process(inputs map[int][]float32, sampleRate int) ([]float32, error) {
+	recs := make(map[int]record.Record, len(inputs))
+	for ch, samples := range inputs {
+		recs[ch] = record.Record{Samples: samples, SampleRate: sampleRate}
+	}
+
+	out, err := s.expr.eval(recs, s.carries)
+	if err != nil {
+		return nil, err
+	}
+	return out.Samples, nil
+}
+
+// node is one operation in the graph. eval returns a fresh slice of
+// env.length values.
+type node interface {
+	eval(env env) []float64
+	channels(acc []int) []int
+}
+
+type constNode float64
+
+func (n constNode) eval(env env) []float64 {
+	out := make([]float64, env.length)
+	for i := range out {
+		out[i] = float64(n)
+	}
+	return out
+}
+
+func (n constNode) channels(acc []int) []int { return acc }
+
+type channelNode int
+
+func (n channelNode) eval(env env) []float64 {
+	in := env.inputs[int(n)].Samples
+	out := make([]float64, len(in))
+	for i, v := range in {
+		out[i] = float64(v)
+	}
+	return out
+}
+
+func (n channelNode) channels(acc []int) []int {
+	if i, found := slices.BinarySearch(acc, int(n)); !found {
+		acc = slices.Insert(acc, i, int(n))
+	}
+	return acc
+}
+
+type binaryNode struct {
+	op          byte
+	left, right node
+}
+
+func (n *binaryNode) eval(env env) []float64 {
+	out := n.left.eval(env)
+	r := n.right.eval(env)
+	for i := range out {
+		switch n.op {
+		case '+':
+			out[i] += r[i]
+		case '-':
+			out[i] -= r[i]
+		case '*':
+			out[i] *= r[i]
+		case '/':
+			out[i] /= r[i]
+		}
+	}
+	return out
+}
+
+func (n *binaryNode) channels(acc []int) []int {
+	return n.right.channels(n.left.channels(acc))
+}
+
+type negNode struct{ arg node }
+
+func (n *negNode) eval(env env) []float64 {
+	out := n.arg.eval(env)
+	for i := range out {
+		out[i] = -out[i]
+	}
+	return out
+}
+
+func (n *negNode) channels(acc []int) []int { return n.arg.channels(acc) }
+
+type absNode struct{ arg node }
+
+func (n *absNode) eval(env env) []float64 {
+	out := n.arg.eval(env)
+	for i := range out {
+		out[i] = math.Abs(out[i])
+	}
+	return out
+}
+
+func (n *absNode) channels(acc []int) []int { return n.arg.channels(acc) }
+
+// integNode is the running trapezoidal integral in full-scale seconds,
+// starting from zero at the first sample.
+type integNode struct{ arg node }
+
+func (n *integNode) eval(env env) []float64 {
+	in := n.arg.eval(env)
+	out := make([]float64, len(in))
+	if len(in) == 0 {
+		return out
+	}
+
+	dt := 1 / float64(env.rate)
+	c := env.carried(n)
+	if c != nil && c.started {
+		out[0] = c.sum + (c.prev+in[0])/2*dt
+	}
+	for i := 1; i < len(in); i++ {
+		out[i] = out[i-1] + (in[i-1]+in[i])/2*dt
+	}
+
+	if c != nil {
+		c.started, c.prev, c.sum = true, in[len(in)-1], out[len(out)-1]
+	}
+	return out
+}
+
+func (n *integNode) channels(acc []int) []int { return n.arg.channels(acc) }
+
+// diffNode is the derivative in full scale per second, by central
+// differences inside the record and one-sided ones at its ends.
+type diffNode struct{ arg node }
+
+func (n *diffNode) eval(env env) []float64 {
+	in := n.arg.eval(env)
+	out := make([]float64, len(in))
+	rate := float64(env.rate)
+
+	if c := env.carried(n); c != nil && len(in) > 0 {
+		prev := c.prev
+		if !c.started {
+			prev = in[0]
+		}
+		for i, v := range in {
+			out[i] = (v - prev) * rate
+			prev = v
+		}
+		c.started, c.prev = true, prev
+		return out
+	}
+
+	if len(in) < 2 {
+		return out
+	}
+
+	last := len(in) - 1
+	out[0] = (in[1] - in[0]) * rate
+	out[last] = (in[last] - in[last-1]) * rate
+	for i := 1; i < last; i++ {
+		out[i] = (in[i+1] - in[i-1]) / 2 * rate
+	}
+	return out
+}
+
+func (n *diffNode) channels(acc []int) []int { return n.arg.channels(acc) }
@@ -0,0 +1,8 @@
+package mathchan
+
+// functions maps each function name to the node it builds.
+var functions = map[string]func(arg node) node{
+	"integ": func(arg node) node { return &integNode{arg: arg} },
+	"diff":  func(arg node) node { return &diffNode{arg: arg} },
+	"abs":   func(arg node) node { return &absNode{arg: arg} },
+}
@@ -0,0 +1,192 @@
+package mathchan
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"oscilloscope/internal/record"
+)
+
+const testRate = 1000
+
+func rec(ch int, samples ...float32) record.Record {
+	return record.Record{Samples: samples, SampleRate: testRate, Channel: ch, TriggerIndex: 1}
+}
+
+func eval(t *testing.T, src string, inputs map[int]record.Record) []float32 {
+	t.Helper()
+	e, err := Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := e.Eval(inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out.Samples
+}
+
+func near(a, b []float32) bool {
+	return slices.EqualFunc(a, b, func(x, y float32) bool { return math.Abs(float64(x-y)) < 1e-5 })
+}
+
+func TestArithmetic(t *testing.T) {
+	inputs := map[int]record.Record{
+		0: rec(0, 1, 2, 3, 4),
+		1: rec(1, 0.5, 0.5, -1, 2),
+	}
+
+	tests := []struct {
+		src  string
+		want []float32
+	}{
+		{"ch1+ch2", []float32{1.5, 2.5, 2, 6}},
+		{"(ch1-ch2)/2", []float32{0.25, 0.75, 2, 1}},
+		{"ch1*ch2", []float32{0.5, 1, -3, 8}},
+		{"-ch1", []float32{-1, -2, -3, -4}},
+		{"ch1 + ch2 * 2", []float32{2, 3, 1, 8}},
+		{"-(ch1 - 1) * -2", []float32{0, 2, 4, 6}},
+		{"abs(ch2)", []float32{0.5, 0.5, 1, 2}},
+		{"CH1 / 0.5", []float32{2, 4, 6, 8}},
+	}
+
+	for _, tt := range tests {
+		if got := eval(t, tt.src, inputs); !near(got, tt.want) {
+			t.Errorf("%s = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestCalculus(t *testing.T) {
+	// A ramp rising 1 FS per second
+	ramp := make([]float32, 5)
+	for i := range ramp {
+		ramp[i] = float32(i) / testRate
+	}
+	inputs := map[int]record.Record{0: rec(0, ramp...)}
+
+	if got := eval(t, "diff(ch1)", inputs); !near(got, []float32{1, 1, 1, 1, 1}) {
+		t.Errorf("diff(ramp) = %v, want all 1", got)
+	}
+
+	ones := map[int]record.Record{0: rec(0, 1, 1, 1, 1)}
+	if got := eval(t, "integ(ch1)", ones); !near(got, []float32{0, 0.001, 0.002, 0.003}) {
+		t.Errorf("integ(1) = %v, want a ramp of 1/s", got)
+	}
+
+	// Differentiating the integral gives back the input away from the
+	// one-sided ends
+	if got := eval(t, "diff(integ(ch1))", inputs); !near(got[1:4], ramp[1:4]) {
+		t.Errorf("diff(integ(ramp)) = %v, want %v inside", got, ramp)
+	}
+}
+
+func TestEvalKeepsMetadata(t *testing.T) {
+	e, err := Parse("ch2 - ch1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(e.Channels(), []int{0, 1}) {
+		t.Fatalf("channels = %v, want [0 1]", e.Channels())
+	}
+
+	out, err := e.Eval(map[int]record.Record{0: rec(0, 1), 1: rec(1, 3)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Channel != 0 || out.SampleRate != testRate || out.TriggerIndex != 1 {
+		t.Fatalf("metadata not taken from ch1: %+v", out)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	for _, src := range []string{"", "ch1 +", "(ch1", "ch0", "sqrt(ch1)", "ch1 ch2", "integ ch1", "1..2", "ch1 $"} {
+		if _, err := Parse(src); err == nil {
+			t.Errorf("Parse(%q) should fail", src)
+		}
+	}
+
+	e, _ := Parse("ch1 + ch2")
+	if _, err := e.Eval(map[int]record.Record{0: rec(0, 1)}); err == nil {
+		t.Error("a missing channel should fail")
+	}
+	if _, err := e.Eval(map[int]record.Record{0: rec(0, 1), 1: rec(1, 1, 2)}); err == nil {
+		t.Error("records of different lengths should fail")
+	}
+
+	e, _ = Parse("2 * 3")
+	if _, err := e.Eval(map[int]record.Record{0: rec(0, 1)}); err == nil {
+		t.Error("an expression without channels should fail")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	for src, want := range map[string]bool{"-ch1": true, "ch1 - ch2": false, "2 * 3": false} {
+		e, err := Parse(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok := e.Check(1) == nil; ok != want {
+			t.Errorf("Check(1) on %q passed = %v, want %v", src, ok, want)
+		}
+	}
+
+	e, _ := Parse("ch1 - ch2")
+	if err := e.Check(2); err != nil {
+		t.Errorf("Check(2) on %q: %v", e, err)
+	}
+}
+
+func TestInputsIncludeSiblings(t *testing.T) {
+	r := rec(0, 1, 2)
+	r.Siblings = []record.Record{rec(1, 0.5, 3)}
+
+	if got, want := eval(t, "ch1-ch2", Inputs(r)), []float32{0.5, -1}; !near(got, want) {
+		t.Errorf("ch1-ch2 = %v, want %v", got, want)
+	}
+}
+
+// A stream split into blocks must integrate as if it were one record, and
+// differentiate without a jump at the block boundaries.
+func TestStreamCarriesAcrossBlocks(t *testing.T) {
+	in := make([]float32, 12)
+	for i := range in {
+		in[i] = float32(i*i) / testRate
+	}
+
+	e, err := Parse("integ(ch1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	whole, err := e.Eval(map[int]record.Record{0: rec(0, in...)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := e.NewStream()
+	var blocks []float32
+	for i := 0; i < len(in); i += 5 {
+		out, err := s.Process(map[int][]float32{0: in[i:min(i+5, len(in))]}, testRate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		blocks = append(blocks, out...)
+	}
+	if !near(blocks, whole.Samples) {
+		t.Errorf("integ in blocks = %v, want %v", blocks, whole.Samples)
+	}
+
+	e, _ = Parse("diff(ch1)")
+	s = e.NewStream()
+	blocks = blocks[:0]
+	for i := 0; i < len(in); i += 5 {
+		out, _ := s.Process(map[int][]float32{0: in[i:min(i+5, len(in))]}, testRate)
+		blocks = append(blocks, out...)
+	}
+	for i := 1; i < len(in); i++ {
+		if want := float32(2*i - 1); math.Abs(float64(blocks[i]-want)) > 1e-3 {
+			t.Fatalf("diff at %d = %g, want %g", i, blocks[i], want)
+		}
+	}
+}
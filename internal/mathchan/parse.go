@@ -0,0 +1,173 @@
+package mathchan
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// The grammar, lowest precedence first:
+//
+//	expr    = term { ("+" | "-") term }
+//	term    = unary { ("*" | "/") unary }
+//	unary   = "-" unary | primary
+//	primary = number | "ch" digits | name "(" expr ")" | "(" expr ")"
+
+type parser struct {
+	src string
+	pos int
+}
+
+// Parse compiles src into an expression over record channels, where chN
+// names channel N-1.
+func Parse(src string) (*Expr, error) {
+	p := &parser{src: src}
+
+	root, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return nil, p.errorf("unexpected %q", p.src[p.pos:p.pos+1])
+	}
+
+	return &Expr{src: src, root: root, channels: root.channels(nil)}, nil
+}
+
+func (p *parser) expr() (node, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op, ok := p.accept("+-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) term() (node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op, ok := p.accept("*/")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) unary() (node, error) {
+	if _, ok := p.accept("-"); ok {
+		arg, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &negNode{arg: arg}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (node, error) {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return nil, p.errorf("unexpected end of expression")
+	}
+
+	c := rune(p.src[p.pos])
+	switch {
+	case c == '(':
+		p.pos++
+		n, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.accept(")"); !ok {
+			return nil, p.errorf("missing )")
+		}
+		return n, nil
+
+	case unicode.IsDigit(c) || c == '.':
+		start := p.pos
+		for p.pos < len(p.src) && (unicode.IsDigit(rune(p.src[p.pos])) || p.src[p.pos] == '.') {
+			p.pos++
+		}
+		text := p.src[start:p.pos]
+		v, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			p.pos = start
+			return nil, p.errorf("bad number %q", text)
+		}
+		return constNode(v), nil
+
+	case unicode.IsLetter(c):
+		start := p.pos
+		for p.pos < len(p.src) && (unicode.IsLetter(rune(p.src[p.pos])) || unicode.IsDigit(rune(p.src[p.pos]))) {
+			p.pos++
+		}
+		name := strings.ToLower(p.src[start:p.pos])
+
+		if build, ok := functions[name]; ok {
+			if _, ok := p.accept("("); !ok {
+				return nil, p.errorf("missing ( after %s", name)
+			}
+			arg, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := p.accept(")"); !ok {
+				return nil, p.errorf("missing )")
+			}
+			return build(arg), nil
+		}
+
+		if digits, ok := strings.CutPrefix(name, "ch"); ok {
+			if n, err := strconv.Atoi(digits); err == nil && n >= 1 {
+				return channelNode(n - 1), nil
+			}
+		}
+
+		p.pos = start
+		return nil, p.errorf("unknown name %q", name)
+	}
+
+	return nil, p.errorf("unexpected %q", string(c))
+}
+
+// accept consumes the next non-space byte if it is one of chars.
+func (p *parser) accept(chars string) (byte, bool) {
+	p.skipSpace()
+	if p.pos < len(p.src) && strings.IndexByte(chars, p.src[p.pos]) >= 0 {
+		p.pos++
+		return p.src[p.pos-1], true
+	}
+	return 0, false
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("mathchan: column %d: %s", p.pos+1, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,314 @@
+package measure
+
+import (
+	"errors"
+	"math"
+
+	"oscilloscope/internal/record"
+)
+
+var errTooShort = errors.New("measure: record too short")
+
+// Result holds the measurements of one record. Times are in seconds,
+// Overshoot and DutyCycle are fractions. Values that need a complete cycle
+// or edge the record does not contain are NaN.
+type Result struct {
+	Frequency float64
+	Period    float64
+
+	PeakToPeak float64
+	Max        float64
+	Min        float64
+	Mean       float64
+	RMS        float64
+	ACRMS      float64
+
+	Amplitude float64
+	Top       float64
+	Base      float64
+	Overshoot float64
+
+	RiseTime      float64
+	FallTime      float64
+	PositiveWidth float64
+	NegativeWidth float64
+	DutyCycle     float64
+
+	CrestFactor float64
+}
+
+// Measure computes every measurement for rec. The period is taken from the
+// trigger point at TriggerIndex+TriggerOffset to the last crossing of the
+// same kind, and mean and RMS are taken over those whole cycles, so they do
+// not depend on where the record happens to end.
+func Measure(rec record.Record) (Result, error) {
+	s := rec.Samples
+	if len(s) < 2 || rec.SampleRate <= 0 {
+		return Result{}, errTooShort
+	}
+	dt := 1 / float64(rec.SampleRate)
+
+	var r Result
+
+	r.Min, r.Max = float64(s[0]), float64(s[0])
+	for _, v := range s[1:] {
+		r.Min = math.Min(r.Min, float64(v))
+		r.Max = math.Max(r.Max, float64(v))
+	}
+	r.PeakToPeak = r.Max - r.Min
+
+	r.Top, r.Base = topBase(s, r.Min, r.Max)
+	r.Amplitude = r.Top - r.Base
+	r.Overshoot = math.NaN()
+	if r.Amplitude > 0 {
+		r.Overshoot = (r.Max - r.Top) / r.Amplitude
+	}
+
+	first, last, cycles := period(rec)
+	r.Period, r.Frequency = math.NaN(), math.NaN()
+	if cycles > 0 {
+		r.Period = (last - first) / float64(cycles) * dt
+		r.Frequency = 1 / r.Period
+	} else {
+		first, last = 0, float64(len(s)-1)
+	}
+
+	r.Mean, r.RMS, r.ACRMS = moments(s, first, last)
+	r.CrestFactor = math.NaN()
+	if r.RMS > 0 {
+		r.CrestFactor = math.Max(math.Abs(r.Max), math.Abs(r.Min)) / r.RMS
+	}
+
+	low := r.Base + LowRef*r.Amplitude
+	mid := r.Base + MidRef*r.Amplitude
+	high := r.Base + HighRef*r.Amplitude
+
+	r.RiseTime = transition(s, 1, low, high) * dt
+	r.FallTime = transition(s, -1, -high, -low) * dt
+
+	r.PositiveWidth, r.NegativeWidth = widths(s, low, mid, high)
+	r.PositiveWidth *= dt
+	r.NegativeWidth *= dt
+	r.DutyCycle = r.PositiveWidth / r.Period
+
+	return r, nil
+}
+
+// period returns the trigger point, the last crossing that matches it and
+// the number of whole cycles between them. Without a trigger point inside
+// the record the rising mid-level crossings are used instead.
+func period(rec record.Record) (first, last float64, cycles int) {
+	s := rec.Samples
+	t := rec.Trigger
+
+	if rec.TriggerIndex >= 0 && rec.TriggerIndex < len(s)-1 && t.Polarity != 0 {
+		// Arm and cross exactly as the trigger does: polarity only mirrors
+		// the arming band, the crossing itself is always rising
+		arm, _ := t.Band()
+
+		first = float64(rec.TriggerIndex) + rec.TriggerOffset
+		from := rec.TriggerIndex + 1
+		edges := crossings(s[from:], 1, t.Level, arm)
+		if len(edges) > 0 {
+			return first, float64(from) + edges[len(edges)-1], len(edges)
+		}
+		return first, first, 0
+	}
+
+	mn, mx := float64(s[0]), float64(s[0])
+	for _, v := range s {
+		mn = math.Min(mn, float64(v))
+		mx = math.Max(mx, float64(v))
+	}
+	edges := crossings(s, 1, (mn+mx)/2, mn+LowRef*(mx-mn))
+	if len(edges) < 2 {
+		return 0, 0, 0
+	}
+	return edges[0], edges[len(edges)-1], len(edges) - 1
+}
+
+// crossings returns the sub-sample positions where dir*s rises through
+// level, counting a crossing only after the signal has been at or below arm
+// since the previous one, the same hysteresis the trigger uses.
+func crossings(s []float32, dir, level, arm float64) []float64 {
+	var out []float64
+	armed := false
+	for i := 1; i < len(s); i++ {
+		prev := dir * float64(s[i-1])
+		curr := dir * float64(s[i])
+
+		if prev <= arm {
+			armed = true
+		}
+		if armed && prev < level && curr >= level {
+			out = append(out, float64(i-1)+(level-prev)/(curr-prev))
+			armed = false
+		}
+	}
+	return out
+}
+
+// topBase finds the most common level in the upper and lower half of the
+// range. A half without a clear plateau falls back to the extreme.
+func topBase(s []float32, mn, mx float64) (top, base float64) {
+	span := mx - mn
+	if span == 0 {
+		return mx, mn
+	}
+
+	var counts [histogramBins]int
+	var sums [histogramBins]float64
+	for _, v := range s {
+		b := min(int((float64(v)-mn)/span*histogramBins), histogramBins-1)
+		counts[b]++
+		sums[b] += float64(v)
+	}
+
+	mode := func(lo, hi int) (float64, bool) {
+		best := lo
+		for b := lo; b < hi; b++ {
+			if counts[b] > counts[best] {
+				best = b
+			}
+		}
+		if float64(counts[best]) < plateauFraction*float64(len(s)) {
+			return 0, false
+		}
+		return sums[best] / float64(counts[best]), true
+	}
+
+	top, ok := mode(histogramBins/2, histogramBins)
+	if !ok {
+		top = mx
+	}
+	base, ok = mode(0, histogramBins/2)
+	if !ok {
+		base = mn
+	}
+	return top, base
+}
+
+// moments returns the mean, RMS and AC RMS of the samples between the
+// sub-sample positions first and last.
+func moments(s []float32, first, last float64) (mean, rms, acrms float64) {
+	lo := int(math.Ceil(first))
+	hi := min(int(math.Floor(last)), len(s)-1)
+	if hi <= lo {
+		lo, hi = 0, len(s)-1
+	}
+
+	var sum, sq float64
+	for _, v := range s[lo : hi+1] {
+		sum += float64(v)
+		sq += float64(v) * float64(v)
+	}
+	n := float64(hi - lo + 1)
+
+	mean = sum / n
+	rms = math.Sqrt(sq / n)
+	acrms = math.Sqrt(math.Max(sq/n-mean*mean, 0))
+	return mean, rms, acrms
+}
+
+// transition returns the mean time in samples that dir*s takes to rise from
+// low to high, over every complete edge in the record.
+func transition(s []float32, dir, low, high float64) float64 {
+	starts := crossings(s, dir, low, low)
+	ends := crossings(s, dir, high, low)
+
+	var total float64
+	var n int
+	for _, end := range ends {
+		// The edge starts at the last low crossing before it
+		var start float64 = -1
+		for _, t := range starts {
+			if t > end {
+				break
+			}
+			start = t
+		}
+		if start < 0 {
+			continue
+		}
+		total += end - start
+		n++
+	}
+	if n == 0 {
+		return math.NaN()
+	}
+	return total / float64(n)
+}
+
+// widths returns the mean time in samples between a rising and the next
+// falling mid-level crossing, and between a falling and the next rising one.
+func widths(s []float32, low, mid, high float64) (positive, negative float64) {
+	rising := crossings(s, 1, mid, low)
+	falling := crossings(s, -1, -mid, -high)
+
+	return spacing(rising, falling), spacing(falling, rising)
+}
+
+// spacing returns the mean distance from each entry of from to the first
+// entry of to after it.
+func spacing(from, to []float64) float64 {
+	var total float64
+	var n int
+	j := 0
+	for _, f := range from {
+		for j < len(to) && to[j] <= f {
+			j++
+		}
+		if j == len(to) {
+			break
+		}
+		total += to[j] - f
+		n++
+	}
+	if n == 0 {
+		return math.NaN()
+	}
+	return total / float64(n)
+}
+
+// Value returns the measurement named by k.
+func (r Result) Value(k Kind) float64 {
+	switch k {
+	case Frequency:
+		return r.Frequency
+	case Period:
+		return r.Period
+	case PeakToPeak:
+		return r.PeakToPeak
+	case Max:
+		return r.Max
+	case Min:
+		return r.Min
+	case Mean:
+		return r.Mean
+	case RMS:
+		return r.RMS
+	case ACRMS:
+		return r.ACRMS
+	case Amplitude:
+		return r.Amplitude
+	case Top:
+		return r.Top
+	case Base:
+		return r.Base
+	case Overshoot:
+		return r.Overshoot
+	case RiseTime:
+		return r.RiseTime
+	case FallTime:
+		return r.FallTime
+	case PositiveWidth:
+		return r.PositiveWidth
+	case NegativeWidth:
+		return r.NegativeWidth
+	case DutyCycle:
+		return r.DutyCycle
+	case CrestFactor:
+		return r.CrestFactor
+	}
+	return math.NaN()
+}
@@ -0,0 +1,71 @@
+package measure
+
+// Reference levels as a fraction of the top-to-base amplitude.
+const (
+	LowRef  = 0.1
+	MidRef  = 0.5
+	HighRef = 0.9
+)
+
+const (
+	// histogramBins is the resolution used to find the top and base
+	// plateaus.
+	histogramBins = 100
+	// plateauFraction is the share of samples a histogram bin must hold
+	// for it to count as a flat top or base; below it the extremes are
+	// used instead, as for sines and triangles.
+	plateauFraction = 0.1
+)
+
+// Kind names one measurement in a Result.
+type Kind int
+
+const (
+	Frequency Kind = iota
+	Period
+	PeakToPeak
+	Max
+	Min
+	Mean
+	RMS
+	ACRMS
+	Amplitude
+	Top
+	Base
+	Overshoot
+	RiseTime
+	FallTime
+	PositiveWidth
+	NegativeWidth
+	DutyCycle
+	CrestFactor
+	kindCount
+)
+
+var kindNames = [kindCount]string{
+	Frequency:     "FREQ",
+	Period:        "PERIOD",
+	PeakToPeak:    "PK-PK",
+	Max:           "MAX",
+	Min:           "MIN",
+	Mean:          "MEAN",
+	RMS:           "RMS",
+	ACRMS:         "AC RMS",
+	Amplitude:     "AMPL",
+	Top:           "TOP",
+	Base:          "BASE",
+	Overshoot:     "OVERSHOOT",
+	RiseTime:      "RISE",
+	FallTime:      "FALL",
+	PositiveWidth: "+WIDTH",
+	NegativeWidth: "-WIDTH",
+	DutyCycle:     "DUTY",
+	CrestFactor:   "CREST",
+}
+
+func (k Kind) String() string {
+	if k < 0 || k >= kindCount {
+		return "UNKNOWN"
+	}
+	return kindNames[k]
+}
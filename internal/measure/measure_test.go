@@ -0,0 +1,209 @@
+package measure
+
+import (
+	"math"
+	"testing"
+
+	"oscilloscope/internal/record"
+	"oscilloscope/internal/trigger"
+)
+
+const (
+	testRate = 48000
+	testFreq = 440.0 // a period of 109.09 samples, not a whole number
+	testLen  = 4096
+)
+
+// signal builds a record from f, which takes the phase in cycles, with the
+// trigger set on the first armed rising zero crossing.
+func signal(f func(phase float64) float64) record.Record {
+	samples := make([]float32, testLen)
+	for i := range samples {
+		samples[i] = float32(f(float64(i) * testFreq / testRate))
+	}
+
+	rec := record.Record{
+		Samples:      samples,
+		SampleRate:   testRate,
+		Trigger:      *trigger.New(),
+		TriggerIndex: -1,
+	}
+
+	armed := false
+	for i := 1; i < len(samples); i++ {
+		prev, curr := float64(samples[i-1]), float64(samples[i])
+		if prev <= rec.Trigger.Lower {
+			armed = true
+		}
+		if armed && prev < 0 && curr >= 0 {
+			rec.TriggerIndex = i - 1
+			rec.TriggerOffset = -prev / (curr - prev)
+			break
+		}
+	}
+	return rec
+}
+
+func sine(a, dc float64) func(float64) float64 {
+	return func(p float64) float64 { return dc + a*math.Sin(2*math.Pi*(p+0.37)) }
+}
+
+func triangle(a float64) func(float64) float64 {
+	return func(p float64) float64 {
+		x := math.Mod(p+0.37, 1)
+		return a * (1 - 4*math.Abs(x-0.5))
+	}
+}
+
+// square clips a triangle five times larger, so each edge is a straight
+// ramp taking a tenth of the period.
+func square(a, dc float64) func(float64) float64 {
+	tri := triangle(5 * a)
+	return func(p float64) float64 { return dc + math.Max(-a, math.Min(a, tri(p))) }
+}
+
+func TestMeasureKnownSignals(t *testing.T) {
+	period := 1 / testFreq
+	sq := math.Sqrt(0.8 + 0.2/3)
+
+	tests := []struct {
+		name string
+		rec  record.Record
+		want Result
+	}{
+		{
+			name: "sine",
+			rec:  signal(sine(0.5, 0)),
+			want: Result{
+				Frequency: testFreq, Period: period,
+				PeakToPeak: 1, Max: 0.5, Min: -0.5, Mean: 0,
+				RMS: 0.5 / math.Sqrt2, ACRMS: 0.5 / math.Sqrt2,
+				Amplitude: 1, Top: 0.5, Base: -0.5, Overshoot: 0,
+				RiseTime:      math.Asin(0.8) / math.Pi * period,
+				FallTime:      math.Asin(0.8) / math.Pi * period,
+				PositiveWidth: period / 2, NegativeWidth: period / 2,
+				DutyCycle: 0.5, CrestFactor: math.Sqrt2,
+			},
+		},
+		{
+			name: "square with offset",
+			rec:  signal(square(0.5, 0.1)),
+			want: Result{
+				Frequency: testFreq, Period: period,
+				PeakToPeak: 1, Max: 0.6, Min: -0.4, Mean: 0.1,
+				RMS: math.Sqrt(0.25*sq*sq + 0.01), ACRMS: 0.5 * sq,
+				Amplitude: 1, Top: 0.6, Base: -0.4, Overshoot: 0,
+				RiseTime: 0.08 * period, FallTime: 0.08 * period,
+				PositiveWidth: period / 2, NegativeWidth: period / 2,
+				DutyCycle: 0.5, CrestFactor: 0.6 / math.Sqrt(0.25*sq*sq+0.01),
+			},
+		},
+		{
+			name: "triangle",
+			rec:  signal(triangle(0.8)),
+			want: Result{
+				Frequency: testFreq, Period: period,
+				PeakToPeak: 1.6, Max: 0.8, Min: -0.8, Mean: 0,
+				RMS: 0.8 / math.Sqrt(3), ACRMS: 0.8 / math.Sqrt(3),
+				Amplitude: 1.6, Top: 0.8, Base: -0.8, Overshoot: 0,
+				RiseTime: 0.4 * period, FallTime: 0.4 * period,
+				PositiveWidth: period / 2, NegativeWidth: period / 2,
+				DutyCycle: 0.5, CrestFactor: math.Sqrt(3),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Measure(tt.rec)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			check := func(name string, got, want, tol float64) {
+				t.Helper()
+				if math.Abs(got-want) > tol {
+					t.Errorf("%s = %g, want %g", name, got, want)
+				}
+			}
+
+			check("Frequency", got.Frequency, tt.want.Frequency, 0.001*testFreq)
+			check("Period", got.Period, tt.want.Period, 0.001*period)
+			check("PeakToPeak", got.PeakToPeak, tt.want.PeakToPeak, 0.005)
+			check("Max", got.Max, tt.want.Max, 0.005)
+			check("Min", got.Min, tt.want.Min, 0.005)
+			check("Mean", got.Mean, tt.want.Mean, 0.005)
+			check("RMS", got.RMS, tt.want.RMS, 0.005)
+			check("ACRMS", got.ACRMS, tt.want.ACRMS, 0.005)
+			check("Amplitude", got.Amplitude, tt.want.Amplitude, 0.005)
+			check("Top", got.Top, tt.want.Top, 0.005)
+			check("Base", got.Base, tt.want.Base, 0.005)
+			check("Overshoot", got.Overshoot, tt.want.Overshoot, 0.005)
+			check("RiseTime", got.RiseTime, tt.want.RiseTime, 0.01*period)
+			check("FallTime", got.FallTime, tt.want.FallTime, 0.01*period)
+			check("PositiveWidth", got.PositiveWidth, tt.want.PositiveWidth, 0.01*period)
+			check("NegativeWidth", got.NegativeWidth, tt.want.NegativeWidth, 0.01*period)
+			check("DutyCycle", got.DutyCycle, tt.want.DutyCycle, 0.01)
+			check("CrestFactor", got.CrestFactor, tt.want.CrestFactor, 0.01)
+		})
+	}
+}
+
+func TestMeasureUsesTriggerOffset(t *testing.T) {
+	rec := signal(sine(0.5, 0))
+	want, err := Measure(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Dropping the sub-sample offset moves the start of the first cycle
+	// and must show up in the period
+	rec.TriggerOffset = 0
+	got, err := Measure(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Period == want.Period {
+		t.Fatalf("period %g ignores TriggerOffset", got.Period)
+	}
+}
+
+func TestMeasureTooShort(t *testing.T) {
+	if _, err := Measure(record.Record{SampleRate: testRate}); err == nil {
+		t.Fatal("expected an error for an empty record")
+	}
+}
+
+func TestMeasureWithoutCycleIsNaN(t *testing.T) {
+	rec := record.Record{
+		Samples:      []float32{0, 0.1, 0.2, 0.3},
+		SampleRate:   testRate,
+		TriggerIndex: -1,
+	}
+
+	got, err := Measure(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !math.IsNaN(got.Frequency) || !math.IsNaN(got.DutyCycle) {
+		t.Fatalf("got frequency %g, duty %g, want NaN", got.Frequency, got.DutyCycle)
+	}
+	if got.Max != float64(float32(0.3)) {
+		t.Fatalf("Max = %g, want 0.3", got.Max)
+	}
+}
+
+// The trigger fires on rising crossings whatever its polarity, so the
+// period must be counted between rising crossings too.
+func TestMeasureNegativePolarity(t *testing.T) {
+	rec := signal(sine(0.5, 0))
+	rec.Trigger.Polarity = trigger.Negative
+
+	r, err := Measure(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(r.Frequency-testFreq) > 0.01 {
+		t.Fatalf("frequency = %g, want %g", r.Frequency, testFreq)
+	}
+}
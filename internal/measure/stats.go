@@ -0,0 +1,126 @@
+package measure
+
+import (
+	"math"
+	"sync"
+)
+
+// Key identifies one running statistic.
+type Key struct {
+	Kind    Kind
+	Channel int
+}
+
+// Stat summarises every reading of one measurement since the last reset.
+type Stat struct {
+	Current float64
+	Min     float64
+	Max     float64
+	Mean    float64
+	Count   int
+
+	m2 float64 // sum of squared deviations from Mean
+}
+
+// Add folds v into the statistic using Welford's method.
+func (s *Stat) Add(v float64) {
+	s.Current = v
+	s.Count++
+	if s.Count == 1 {
+		s.Min, s.Max, s.Mean, s.m2 = v, v, v, 0
+		return
+	}
+
+	s.Min = math.Min(s.Min, v)
+	s.Max = math.Max(s.Max, v)
+
+	delta := v - s.Mean
+	s.Mean += delta / float64(s.Count)
+	s.m2 += delta * (v - s.Mean)
+}
+
+// StdDev is the sample standard deviation, zero until there are two
+// readings.
+func (s Stat) StdDev() float64 {
+	if s.Count < 2 {
+		return 0
+	}
+	return math.Sqrt(s.m2 / float64(s.Count-1))
+}
+
+// Statistics keeps a Stat per measurement and channel. It is safe for
+// concurrent use, so the runner feeding it and the display or exporters
+// reading it need no coordination.
+type Statistics struct {
+	mu    sync.Mutex
+	stats map[Key]*Stat
+}
+
+func NewStatistics() *Statistics {
+	return &Statistics{stats: make(map[Key]*Stat)}
+}
+
+// Add records every defined measurement in r for channel. NaN values, for
+// measurements the record could not provide, are skipped.
+func (s *Statistics) Add(channel int, r Result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for k := range kindCount {
+		v := r.Value(k)
+		if math.IsNaN(v) {
+			continue
+		}
+
+		key := Key{Kind: k, Channel: channel}
+		st, ok := s.stats[key]
+		if !ok {
+			st = &Stat{}
+			s.stats[key] = st
+		}
+		st.Add(v)
+	}
+}
+
+func (s *Statistics) Get(key Key) (Stat, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.stats[key]
+	if !ok {
+		return Stat{}, false
+	}
+	return *st, true
+}
+
+// Snapshot returns a copy of every statistic.
+func (s *Statistics) Snapshot() map[Key]Stat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make(map[Key]Stat, len(s.stats))
+	for k, st := range s.stats {
+		out[k] = *st
+	}
+	return out
+}
+
+// Reset clears every statistic.
+func (s *Statistics) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clear(s.stats)
+}
+
+// ResetChannel clears the statistics of one channel.
+func (s *Statistics) ResetChannel(channel int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for k := range s.stats {
+		if k.Channel == channel {
+			delete(s.stats, k)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package measure
+
+import (
+	"context"
+
+	"oscilloscope/internal/record"
+)
+
+// StatsRunner measures every record it receives and folds the result into
+// Stats.
+type StatsRunner struct {
+	Records <-chan record.Record
+	Stats   *Statistics
+}
+
+func NewStatsRunner(records <-chan record.Record, stats *Statistics) *StatsRunner {
+	return &StatsRunner{Records: records, Stats: stats}
+}
+
+// Run returns when ctx is cancelled or the record stream is closed.
+func (r *StatsRunner) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case rec, ok := <-r.Records:
+			if !ok {
+				return nil
+			}
+
+			res, err := Measure(rec)
+			if err != nil {
+				continue
+			}
+			r.Stats.Add(rec.Channel, res)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package measure
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStatAdd(t *testing.T) {
+	var s Stat
+	for _, v := range []float64{2, 4, 4, 4, 5, 5, 7, 9} {
+		s.Add(v)
+	}
+
+	if s.Count != 8 || s.Current != 9 || s.Min != 2 || s.Max != 9 || s.Mean != 5 {
+		t.Fatalf("got %+v", s)
+	}
+	if want := math.Sqrt(32.0 / 7); math.Abs(s.StdDev()-want) > 1e-12 {
+		t.Fatalf("StdDev = %g, want %g", s.StdDev(), want)
+	}
+}
+
+func TestStatisticsKeyedByChannel(t *testing.T) {
+	s := NewStatistics()
+	s.Add(0, Result{Frequency: 440, Period: math.NaN()})
+	s.Add(0, Result{Frequency: 442, Period: math.NaN()})
+	s.Add(1, Result{Frequency: 100, Period: math.NaN()})
+
+	st, ok := s.Get(Key{Kind: Frequency, Channel: 0})
+	if !ok || st.Count != 2 || st.Mean != 441 {
+		t.Fatalf("channel 0 frequency = %+v, %v", st, ok)
+	}
+	if _, ok := s.Get(Key{Kind: Period, Channel: 0}); ok {
+		t.Fatal("NaN readings should not create a statistic")
+	}
+
+	s.ResetChannel(0)
+	if _, ok := s.Get(Key{Kind: Frequency, Channel: 0}); ok {
+		t.Fatal("channel 0 should be cleared")
+	}
+	if st, ok := s.Get(Key{Kind: Frequency, Channel: 1}); !ok || st.Current != 100 {
+		t.Fatalf("channel 1 frequency = %+v, %v", st, ok)
+	}
+
+	s.Reset()
+	if len(s.Snapshot()) != 0 {
+		t.Fatal("Reset should clear everything")
+	}
+}
@@ -0,0 +1,110 @@
+package memory
+
+import "context"
+
+// Deep reads across the in-memory ring and an optional history tier as a
+// single absolute-index range. Indices that have left Recent are served
+// from History for as long as it still holds them.
+type Deep struct {
+	Recent  *Ring
+	History *Ring // nil when no history tier is configured
+}
+
+// Snapshot returns the window covered by both tiers together. A gap between
+// the tiers, which happens when spilling fell behind, ends the window at the
+// oldest sample Recent holds.
+func (d *Deep) Snapshot() (Bounds, bool) {
+	recent, ok := d.Recent.Snapshot()
+	if !ok || d.History == nil {
+		return recent, ok
+	}
+
+	history, ok := d.History.Snapshot()
+	if !ok || history.Newest < recent.Oldest {
+		return recent, true
+	}
+
+	return Bounds{Oldest: min(history.Oldest, recent.Oldest), Newest: recent.Newest}, true
+}
+
+func (d *Deep) HasRange(start, end int) bool {
+	b, ok := d.Snapshot()
+	return ok && b.Contains(start, end)
+}
+
+func (d *Deep) ReadRange(start, end int) ([]float32, error) {
+	if end < start {
+		return nil, errOutOfBounds
+	}
+
+	samples := make([]float32, end-start)
+	if err := d.ReadInto(samples, start); err != nil {
+		return nil, err
+	}
+
+	return samples, nil
+}
+
+// ReadInto fills dst from start, taking whatever Recent no longer holds
+// from History.
+func (d *Deep) ReadInto(dst []float32, start int) error {
+	recent, ok := d.Recent.Snapshot()
+	if !ok || d.History == nil || start >= recent.Oldest {
+		return d.Recent.ReadInto(dst, start)
+	}
+
+	split := min(recent.Oldest-start, len(dst))
+	if err := d.History.ReadInto(dst[:split], start); err != nil {
+		return err
+	}
+	if split == len(dst) {
+		return nil
+	}
+
+	return d.Recent.ReadInto(dst[split:], start+split)
+}
+
+// HistoryRunner copies samples from the in-memory ring into the history
+// tier before the producer overwrites them.
+type HistoryRunner struct {
+	Recent   *Ring
+	History  *Ring
+	Notifier *Notifier
+}
+
+const spillChunk = 4096
+
+func (r *HistoryRunner) Run(ctx context.Context) error {
+	buf := make([]float32, spillChunk)
+	next := -1
+	seen := -1
+
+	waiter := r.Notifier.NewWaiter()
+	defer waiter.Close()
+
+	for {
+		newest, ok := waiter.Wait(ctx.Done(), seen)
+		if !ok {
+			return nil
+		}
+		seen = newest
+
+		bounds, ok := r.Recent.Snapshot()
+		if !ok {
+			continue
+		}
+
+		// Anything older than Oldest was overwritten before it could be
+		// spilled; History records the jump as a gap.
+		next = max(next, bounds.Oldest)
+
+		for next < bounds.Newest {
+			n := min(spillChunk, bounds.Newest-next)
+			if err := r.Recent.ReadInto(buf[:n], next); err != nil {
+				break
+			}
+			r.History.WriteBatch(next, buf[:n])
+			next += n
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDeepReadsAcrossTiers(t *testing.T) {
+	history, err := NewMapped(filepath.Join(t.TempDir(), "history"), 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer history.Close()
+
+	recent := New(8)
+	notifier := NewNotifier()
+	runner := &HistoryRunner{Recent: recent, History: history.Ring, Notifier: notifier}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- runner.Run(ctx) }()
+
+	for base := 0; base < 40; base += 4 {
+		recent.WriteBatch(base, []float32{float32(base), float32(base + 1), float32(base + 2), float32(base + 3)})
+		notifier.Publish(base + 3)
+
+		deadline := time.Now().Add(time.Second)
+		for history.NewestIndex() < base+2 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	deep := &Deep{Recent: recent, History: history.Ring}
+
+	if !deep.HasRange(2, 39) {
+		b, _ := deep.Snapshot()
+		t.Fatalf("deep window %+v does not cover [2, 39)", b)
+	}
+
+	samples, err := deep.ReadRange(2, 39)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range samples {
+		if int(v) != 2+i {
+			t.Fatalf("sample %d = %v", 2+i, v)
+		}
+	}
+}
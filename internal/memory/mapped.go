@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"unsafe"
+)
+
+// MappedRing is a Ring whose samples live in a memory-mapped file, used as
+// the large, slow history tier behind the in-memory ring.
+type MappedRing struct {
+	*Ring
+
+	file *os.File
+	data []byte
+}
+
+// NewMapped creates or truncates the file at path and maps a ring of size
+// samples onto it. The size must be a power of two.
+func NewMapped(path string, size int) (*MappedRing, error) {
+	if !IsPowerOfTwo(size) {
+		return nil, fmt.Errorf("memory: history size %d is not a power of two", size)
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("memory: open history: %w", err)
+	}
+
+	n := size * int(unsafe.Sizeof(uint32(0)))
+	if err := f.Truncate(int64(n)); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("memory: size history: %w", err)
+	}
+
+	data, err := mapFile(f, n)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("memory: map history: %w", err)
+	}
+
+	buf := unsafe.Slice((*atomic.Uint32)(unsafe.Pointer(unsafe.SliceData(data))), size)
+
+	return &MappedRing{
+		Ring: newRing(buf),
+		file: f,
+		data: data,
+	}, nil
+}
+
+// Close unmaps and closes the file. The ring must not be used afterwards.
+func (m *MappedRing) Close() error {
+	if err := unmapFile(m.data); err != nil {
+		m.file.Close()
+		return fmt.Errorf("memory: unmap history: %w", err)
+	}
+	return m.file.Close()
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package memory
+
+import (
+	"errors"
+	"os"
+)
+
+func mapFile(f *os.File, size int) ([]byte, error) {
+	return nil, errors.New("memory-mapped history is not supported on this platform")
+}
+
+func unmapFile(data []byte) error {
+	return nil
+}
@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// Notifier publishes the newest written sample index. Each waiter owns a
+// one-slot wake channel, so Publish only stores the index and does a
+// non-blocking send per waiter: it never allocates, which matters on the
+// audio callback. Waiters check the index before blocking, so a publish can
+// never be missed.
+type Notifier struct {
+	newest atomic.Int64
+
+	mu      sync.Mutex // serialises changes to waiters
+	waiters atomic.Pointer[[]chan struct{}]
+}
+
+func NewNotifier() *Notifier {
+	n := &Notifier{}
+	n.newest.Store(-1)
+	n.waiters.Store(&[]chan struct{}{})
+	return n
+}
+
+// Publish records that every sample up to and including newest is written.
+func (n *Notifier) Publish(newest int) {
+	n.newest.Store(int64(newest))
+
+	for _, ch := range *n.waiters.Load() {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Newest returns the last published index, or -1 before the first publish.
+func (n *Notifier) Newest() int {
+	return int(n.newest.Load())
+}
+
+// Waiter receives the notifier's wake-ups for one consumer goroutine.
+type Waiter struct {
+	n    *Notifier
+	wake chan struct{}
+}
+
+// NewWaiter registers a consumer. Close it once the consumer stops.
+func (n *Notifier) NewWaiter() *Waiter {
+	w := &Waiter{n: n, wake: make(chan struct{}, 1)}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	waiters := append(slices.Clone(*n.waiters.Load()), w.wake)
+	n.waiters.Store(&waiters)
+	return w
+}
+
+// Close unregisters the waiter.
+func (w *Waiter) Close() {
+	n := w.n
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	waiters := slices.DeleteFunc(slices.Clone(*n.waiters.Load()), func(ch chan struct{}) bool {
+		return ch == w.wake
+	})
+	n.waiters.Store(&waiters)
+}
+
+// Wait blocks until an index newer than after is published and returns it.
+// It returns false once done is closed.
+func (w *Waiter) Wait(done <-chan struct{}, after int) (int, bool) {
+	for {
+		select {
+		case <-done:
+			return 0, false
+		default:
+		}
+
+		if newest := w.n.Newest(); newest > after {
+			return newest, true
+		}
+
+		select {
+		case <-w.wake:
+		case <-done:
+			return 0, false
+		}
+	}
+}
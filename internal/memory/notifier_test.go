@@ -0,0 +1,68 @@
+package memory
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNotifierPublishBeforeWait(t *testing.T) {
+	n := NewNotifier()
+	n.Publish(127)
+
+	got, ok := n.NewWaiter().Wait(make(chan struct{}), -1)
+	if !ok || got != 127 {
+		t.Fatalf("Wait = %d, %v, want 127, true", got, ok)
+	}
+}
+
+func TestNotifierWakesEveryWaiter(t *testing.T) {
+	n := NewNotifier()
+	done := make(chan struct{})
+	results := make(chan int, 2)
+
+	for range 2 {
+		w := n.NewWaiter()
+		go func() {
+			got, _ := w.Wait(done, 127)
+			results <- got
+		}()
+	}
+
+	n.Publish(127)
+	n.Publish(255)
+
+	for range 2 {
+		select {
+		case got := <-results:
+			if got != 255 {
+				t.Fatalf("waiter got %d, want 255", got)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("waiter not woken")
+		}
+	}
+}
+
+func TestNotifierStopsOnDone(t *testing.T) {
+	n := NewNotifier()
+	done := make(chan struct{})
+	close(done)
+
+	if _, ok := n.NewWaiter().Wait(done, -1); ok {
+		t.Fatal("Wait succeeded after done was closed")
+	}
+}
+
+func TestNotifierPublishDoesNotAllocate(t *testing.T) {
+	n := NewNotifier()
+	w := n.NewWaiter()
+	defer w.Close()
+
+	i := 0
+	if allocs := testing.AllocsPerRun(1000, func() {
+		i++
+		n.Publish(i)
+	}); allocs != 0 {
+		t.Fatalf("Publish allocates %.1f times per call", allocs)
+	}
+}
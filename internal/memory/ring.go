@@ -2,145 +2,273 @@ package memory
 
 import (
 	"errors"
-	"sync"
+	"iter"
+	"math"
+	"sync/atomic"
 )
 
+// Ring is a single-producer, multi-consumer sample ring addressed by
+// absolute sample index. The producer never takes a lock: it raises oldest
+// before overwriting a slot and publishes newest after writing, and readers
+// copy first and then re-check oldest to discard anything overwritten while
+// they were reading.
 type Ring struct {
-	buf  []float32
+	buf  []atomic.Uint32 // float32 bits
 	size int
 	mask int
 
-	newest int
-	oldest int
-	empty  bool
+	// seq is odd while the producer is moving the bounds, so Snapshot can
+	// read oldest and newest as a consistent pair.
+	seq     atomic.Uint64
+	newest  atomic.Int64
+	oldest  atomic.Int64
+	written atomic.Bool
 
-	mu sync.Mutex
+	stats ringStats
 }
 
+// Bounds is a consistent view of the stored window [Oldest, Newest].
+type Bounds struct {
+	Oldest int
+	Newest int
+}
+
+func (b Bounds) Count() int { return b.Newest - b.Oldest + 1 }
+
+// Contains reports whether [start, end] lies inside the window.
+func (b Bounds) Contains(start, end int) bool {
+	return start >= b.Oldest && end <= b.Newest
+}
+
+var (
+	errOutOfBounds = errors.New("requested range is out of bounds")
+	errOverrun     = errors.New("requested range was overwritten")
+)
+
 func New(size int) *Ring {
-	if size <= 0 || (size&(size-1)) != 0 {
+	if !IsPowerOfTwo(size) {
 		panic("ring size must be a power of two")
 	}
 
+	return newRing(make([]atomic.Uint32, size))
+}
+
+func newRing(buf []atomic.Uint32) *Ring {
 	return &Ring{
-		buf:    make([]float32, size),
-		size:   size,
-		mask:   size - 1,
-		oldest: 0,
-		newest: 0,
-		empty:  true,
+		buf:  buf,
+		size: len(buf),
+		mask: len(buf) - 1,
 	}
 }
 
-func (r *Ring) WriteAt(index int, value float32) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	r.buf[index&r.mask] = value
+func IsPowerOfTwo(n int) bool {
+	return n > 0 && n&(n-1) == 0
+}
 
-	if r.empty {
-		r.oldest = index
-		r.newest = index
-		r.empty = false
-		return
+// NextPowerOfTwo returns the smallest power of two that is at least n.
+func NextPowerOfTwo(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
 	}
+	return p
+}
 
-	if index > r.newest {
-		r.newest = index
-	}
+// WriteAt writes a single sample. Like WriteBatch it must only be called
+// from the producer goroutine.
+func (r *Ring) WriteAt(index int, value float32) {
+	r.WriteBatch(index, []float32{value})
+}
 
-	if r.newest-r.oldest+1 > r.size {
-		r.oldest = r.newest - r.size + 1
+// Snapshot returns the stored window as a consistent pair, or false while
+// the ring is empty.
+func (r *Ring) Snapshot() (Bounds, bool) {
+	for {
+		before := r.seq.Load()
+		if before&1 != 0 {
+			continue
+		}
+
+		written := r.written.Load()
+		b := Bounds{
+			Oldest: int(r.oldest.Load()),
+			Newest: int(r.newest.Load()),
+		}
+
+		if r.seq.Load() == before {
+			return b, written
+		}
 	}
 }
 
 func (r *Ring) ReadAt(index int) (float32, bool) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	if r.empty {
+	b, ok := r.Snapshot()
+	if !ok || !b.Contains(index, index) {
 		return 0, false
 	}
 
-	if index < r.oldest || index > r.newest {
+	v := math.Float32frombits(r.buf[index&r.mask].Load())
+
+	if index < r.OldestIndex() {
 		return 0, false
 	}
 
-	return r.buf[index&r.mask], true
+	return v, true
 }
 
 func (r *Ring) ReadRange(start, end int) ([]float32, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	if r.empty || start < r.oldest || end > r.newest {
-		return nil, errors.New("requested range is out of bounds")
+	if end < start {
+		return nil, errOutOfBounds
 	}
 
-	size := end - start
-	samples := make([]float32, size)
-
-	for i := range size {
-		samples[i] = r.buf[(start+i)&r.mask]
+	samples := make([]float32, end-start)
+	if err := r.ReadInto(samples, start); err != nil {
+		return nil, err
 	}
 
 	return samples, nil
 }
 
 func (r *Ring) HasRange(start, end int) bool {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	if r.empty {
-		return false
-	}
-
-	if start < r.oldest || end > r.newest {
-		return false
-	}
-
-	return true
+	b, ok := r.Snapshot()
+	return ok && b.Contains(start, end)
 }
 
 func (r *Ring) Count() int {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	if r.empty {
+	b, ok := r.Snapshot()
+	if !ok {
 		return 0
 	}
-
-	return r.newest - r.oldest + 1
+	return b.Count()
 }
 
-// WriteBatch writes multiple samples starting at startIndex under a single lock acquisition
+// WriteBatch writes multiple samples starting at startIndex. It must only be
+// called from the producer goroutine.
 func (r *Ring) WriteBatch(startIndex int, values []float32) {
 	if len(values) == 0 {
 		return
 	}
 
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	endIndex := startIndex + len(values) - 1
+
+	newest, oldest := endIndex, startIndex
+	if r.written.Load() {
+		prev := r.NewestIndex()
+		r.stats.recordWrite(startIndex, prev)
+
+		newest = max(prev, endIndex)
+		oldest = r.OldestIndex()
+	}
+	if newest-oldest+1 > r.size {
+		oldest = newest - r.size + 1
+	}
+
+	// Readers must see the window shrink before any slot is reused.
+	r.seq.Add(1)
+	r.oldest.Store(int64(oldest))
+	r.seq.Add(1)
 
 	for i, v := range values {
-		r.buf[(startIndex+i)&r.mask] = v
+		if startIndex+i < oldest {
+			continue
+		}
+		r.buf[(startIndex+i)&r.mask].Store(math.Float32bits(v))
 	}
 
-	endIndex := startIndex + len(values) - 1
+	r.seq.Add(1)
+	r.newest.Store(int64(newest))
+	r.written.Store(true)
+	r.seq.Add(1)
+}
+
+// Stats returns the ring's discontinuity and overrun counters.
+func (r *Ring) Stats() Stats {
+	return r.stats.load()
+}
 
-	if r.empty {
-		r.oldest = startIndex
-		r.newest = endIndex
-		r.empty = false
-	} else if endIndex > r.newest {
-		r.newest = endIndex
+func (r *Ring) Size() int        { return r.size }
+func (r *Ring) NewestIndex() int { return int(r.newest.Load()) }
+func (r *Ring) OldestIndex() int { return int(r.oldest.Load()) }
+
+// ReadInto copies len(dst) samples starting at start into dst without
+// allocating.
+func (r *Ring) ReadInto(dst []float32, start int) error {
+	end := start + len(dst)
+	if err := r.check(start, end); err != nil {
+		return err
 	}
 
-	if r.newest-r.oldest+1 > r.size {
-		r.oldest = r.newest - r.size + 1
+	for i := range dst {
+		dst[i] = math.Float32frombits(r.buf[(start+i)&r.mask].Load())
 	}
+
+	// The producer raises oldest before it overwrites a slot, so if start
+	// is still inside the window nothing we copied was overwritten.
+	if start < r.OldestIndex() {
+		r.stats.overruns.Add(1)
+		return errOverrun
+	}
+
+	return nil
 }
 
-func (r *Ring) Size() int        { return r.size }
-func (r *Ring) NewestIndex() int { return r.newest }
-func (r *Ring) OldestIndex() int { return r.oldest }
+// check validates a read of [start, end) and counts overruns and underruns.
+func (r *Ring) check(start, end int) error {
+	b, ok := r.Snapshot()
+	switch {
+	case !ok || end > b.Newest:
+		r.stats.underruns.Add(1)
+		return errOutOfBounds
+	case start < b.Oldest:
+		r.stats.overruns.Add(1)
+		return errOverrun
+	}
+	return nil
+}
+
+// View returns an in-place window onto [start, end), or false if the range
+// is not in the ring.
+func (r *Ring) View(start, end int) (View, bool) {
+	if r.check(start, end) != nil {
+		return View{}, false
+	}
+	return View{ring: r, Start: start, End: end}, true
+}
+
+// View reads ring samples in place. The producer may overwrite the window
+// while it is being read, so callers check Valid once they are done and
+// discard what they computed if it returns false.
+type View struct {
+	ring       *Ring
+	Start, End int
+}
+
+func (v View) Len() int { return v.End - v.Start }
+
+// At returns the sample at Start+i.
+func (v View) At(i int) float32 {
+	return math.Float32frombits(v.ring.buf[(v.Start+i)&v.ring.mask].Load())
+}
+
+// All yields every sample in the window with its absolute index.
+func (v View) All() iter.Seq2[int, float32] {
+	return func(yield func(int, float32) bool) {
+		for i := range v.Len() {
+			if !yield(v.Start+i, v.At(i)) {
+				return
+			}
+		}
+	}
+}
+
+// Valid reports whether nothing in the window has been overwritten.
+func (v View) Valid() bool {
+	if v.ring == nil {
+		return false
+	}
+	if v.Start < v.ring.OldestIndex() {
+		v.ring.stats.overruns.Add(1)
+		return false
+	}
+	return true
+}
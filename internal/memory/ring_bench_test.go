@@ -0,0 +1,179 @@
+package memory
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// mutexRing is the previous mutex-guarded ring, kept here as the baseline
+// for the lock-free benchmarks.
+type mutexRing struct {
+	buf    []float32
+	size   int
+	mask   int
+	newest int
+	oldest int
+	empty  bool
+	mu     sync.Mutex
+}
+
+func newMutexRing(size int) *mutexRing {
+	return &mutexRing{buf: make([]float32, size), size: size, mask: size - 1, empty: true}
+}
+
+func (r *mutexRing) WriteBatch(startIndex int, values []float32) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, v := range values {
+		r.buf[(startIndex+i)&r.mask] = v
+	}
+
+	endIndex := startIndex + len(values) - 1
+	if r.empty {
+		r.oldest = startIndex
+		r.newest = endIndex
+		r.empty = false
+	} else if endIndex > r.newest {
+		r.newest = endIndex
+	}
+	if r.newest-r.oldest+1 > r.size {
+		r.oldest = r.newest - r.size + 1
+	}
+}
+
+func (r *mutexRing) ReadRange(start, end int) ([]float32, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.empty || start < r.oldest || end > r.newest {
+		return nil, errors.New("requested range is out of bounds")
+	}
+
+	samples := make([]float32, end-start)
+	for i := range samples {
+		samples[i] = r.buf[(start+i)&r.mask]
+	}
+	return samples, nil
+}
+
+func (r *mutexRing) NewestIndex() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.newest
+}
+
+type benchRing interface {
+	WriteBatch(startIndex int, values []float32)
+	ReadRange(start, end int) ([]float32, error)
+	NewestIndex() int
+}
+
+// benchmarkCallback measures one 128-frame audio callback write while
+// readers continuously pull record-sized ranges, as the acquirer and
+// trigger do. Besides the mean it reports the p99 and worst-case callback
+// latency, which is what matters on the audio thread.
+func benchmarkCallback(b *testing.B, r benchRing, readers int) {
+	const batch = 128
+	const readLen = 8192
+
+	buf := make([]float32, batch)
+	base := 0
+	for ; base < MemoryBufferSize; base += batch {
+		r.WriteBatch(base, buf)
+	}
+
+	var stop atomic.Bool
+	var wg sync.WaitGroup
+	for range readers {
+		wg.Go(func() {
+			for !stop.Load() {
+				end := r.NewestIndex()
+				_, _ = r.ReadRange(end-readLen, end)
+			}
+		})
+	}
+
+	latencies := make([]time.Duration, 0, b.N)
+
+	b.SetBytes(batch * 4)
+	b.ResetTimer()
+	for b.Loop() {
+		t0 := time.Now()
+		r.WriteBatch(base, buf)
+		latencies = append(latencies, time.Since(t0))
+		base += batch
+	}
+	b.StopTimer()
+
+	stop.Store(true)
+	wg.Wait()
+
+	slices.Sort(latencies)
+	b.ReportMetric(float64(latencies[len(latencies)*99/100].Nanoseconds()), "p99-ns")
+	b.ReportMetric(float64(latencies[len(latencies)-1].Nanoseconds()), "max-ns")
+}
+
+func BenchmarkCallbackLockFree(b *testing.B) {
+	benchmarkCallback(b, New(MemoryBufferSize), 2)
+}
+
+func BenchmarkCallbackMutex(b *testing.B) {
+	benchmarkCallback(b, newMutexRing(MemoryBufferSize), 2)
+}
+
+func BenchmarkCallbackLockFreeNoReaders(b *testing.B) {
+	benchmarkCallback(b, New(MemoryBufferSize), 0)
+}
+
+func BenchmarkCallbackMutexNoReaders(b *testing.B) {
+	benchmarkCallback(b, newMutexRing(MemoryBufferSize), 0)
+}
+
+func filledRing() *Ring {
+	r := New(MemoryBufferSize)
+	r.WriteBatch(0, make([]float32, MemoryBufferSize))
+	return r
+}
+
+const benchReadLen = 8192
+
+func BenchmarkReadRange(b *testing.B) {
+	r := filledRing()
+	start := r.NewestIndex() - benchReadLen
+
+	b.ReportAllocs()
+	for b.Loop() {
+		_, _ = r.ReadRange(start, start+benchReadLen)
+	}
+}
+
+func BenchmarkReadInto(b *testing.B) {
+	r := filledRing()
+	start := r.NewestIndex() - benchReadLen
+	dst := make([]float32, benchReadLen)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = r.ReadInto(dst, start)
+	}
+}
+
+func BenchmarkView(b *testing.B) {
+	r := filledRing()
+	start := r.NewestIndex() - benchReadLen
+
+	b.ReportAllocs()
+	for b.Loop() {
+		v, _ := r.View(start, start+benchReadLen)
+		var sum float32
+		for _, s := range v.All() {
+			sum += s
+		}
+		_ = v.Valid()
+	}
+}
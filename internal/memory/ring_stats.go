@@ -0,0 +1,48 @@
+package memory
+
+import "sync/atomic"
+
+// Stats counts discontinuities on the write side and data lost or not yet
+// available on the read side.
+type Stats struct {
+	// Gaps counts writes that started past newest+1, and GapSamples the
+	// indices they skipped.
+	Gaps       uint64
+	GapSamples uint64
+	// Rewinds counts writes that started at or before newest.
+	Rewinds uint64
+	// Overruns counts reads of samples that had already been overwritten.
+	Overruns uint64
+	// Underruns counts reads of samples that had not been written yet.
+	Underruns uint64
+}
+
+type ringStats struct {
+	gaps       atomic.Uint64
+	gapSamples atomic.Uint64
+	rewinds    atomic.Uint64
+	overruns   atomic.Uint64
+	underruns  atomic.Uint64
+}
+
+// recordWrite classifies a write starting at start against the previous
+// newest index.
+func (s *ringStats) recordWrite(start, newest int) {
+	switch {
+	case start > newest+1:
+		s.gaps.Add(1)
+		s.gapSamples.Add(uint64(start - newest - 1))
+	case start <= newest:
+		s.rewinds.Add(1)
+	}
+}
+
+func (s *ringStats) load() Stats {
+	return Stats{
+		Gaps:       s.gaps.Load(),
+		GapSamples: s.gapSamples.Load(),
+		Rewinds:    s.rewinds.Load(),
+		Overruns:   s.overruns.Load(),
+		Underruns:  s.underruns.Load(),
+	}
+}
@@ -0,0 +1,140 @@
+package memory
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRingReadsBackWrittenRange(t *testing.T) {
+	r := New(8)
+	r.WriteBatch(10, []float32{1, 2, 3, 4})
+
+	got, err := r.ReadRange(10, 13)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0] != 1 || got[2] != 3 {
+		t.Fatalf("ReadRange = %v, want [1 2 3]", got)
+	}
+	if r.Count() != 4 || r.OldestIndex() != 10 || r.NewestIndex() != 13 {
+		t.Fatalf("count %d, window [%d, %d]", r.Count(), r.OldestIndex(), r.NewestIndex())
+	}
+}
+
+func TestRingDropsOverwrittenSamples(t *testing.T) {
+	r := New(8)
+	r.WriteBatch(0, []float32{0, 1, 2, 3, 4, 5, 6, 7})
+	r.WriteBatch(8, []float32{8, 9, 10})
+
+	if r.HasRange(0, 4) {
+		t.Fatal("overwritten range still reported")
+	}
+	if _, ok := r.ReadAt(2); ok {
+		t.Fatal("ReadAt returned an overwritten sample")
+	}
+	if v, ok := r.ReadAt(10); !ok || v != 10 {
+		t.Fatalf("ReadAt(10) = %v, %v", v, ok)
+	}
+	if r.Count() != 8 || r.OldestIndex() != 3 {
+		t.Fatalf("count %d, oldest %d", r.Count(), r.OldestIndex())
+	}
+}
+
+// TestRingConcurrentReadersSeeConsistentData writes sample i = i and checks
+// that no reader ever returns a torn or overwritten range.
+func TestRingConcurrentReadersSeeConsistentData(t *testing.T) {
+	const batch = 128
+	r := New(1024)
+
+	var stop atomic.Bool
+	var wg sync.WaitGroup
+
+	for range 4 {
+		wg.Go(func() {
+			for !stop.Load() {
+				end := r.NewestIndex()
+				start := end - 512
+				samples, err := r.ReadRange(start, end)
+				if err != nil {
+					continue
+				}
+				for i, v := range samples {
+					if int(v) != start+i {
+						t.Errorf("sample %d = %v", start+i, v)
+						return
+					}
+				}
+			}
+		})
+	}
+
+	buf := make([]float32, batch)
+	for base := 0; base < 1<<18; base += batch {
+		for i := range buf {
+			buf[i] = float32(base + i)
+		}
+		r.WriteBatch(base, buf)
+	}
+
+	stop.Store(true)
+	wg.Wait()
+}
+
+func TestRingViewAndReadInto(t *testing.T) {
+	r := New(8)
+	r.WriteBatch(6, []float32{6, 7, 8, 9, 10})
+
+	dst := make([]float32, 3)
+	if err := r.ReadInto(dst, 7); err != nil || dst[0] != 7 || dst[2] != 9 {
+		t.Fatalf("ReadInto = %v, %v", dst, err)
+	}
+
+	v, ok := r.View(6, 10)
+	if !ok {
+		t.Fatal("View rejected a stored range")
+	}
+	for i, s := range v.All() {
+		if int(s) != i {
+			t.Fatalf("view sample %d = %v", i, s)
+		}
+	}
+	if !v.Valid() {
+		t.Fatal("untouched view reported invalid")
+	}
+
+	r.WriteBatch(11, []float32{11, 12, 13, 14})
+	if v.Valid() {
+		t.Fatal("overwritten view reported valid")
+	}
+}
+
+func TestRingCountsDiscontinuities(t *testing.T) {
+	r := New(8)
+	r.WriteBatch(0, []float32{0, 1, 2, 3})
+	r.WriteBatch(6, []float32{6, 7})
+	r.WriteBatch(7, []float32{7, 8})
+
+	st := r.Stats()
+	if st.Gaps != 1 || st.GapSamples != 2 || st.Rewinds != 1 {
+		t.Fatalf("stats = %+v, want 1 gap of 2 samples and 1 rewind", st)
+	}
+
+	r.WriteBatch(9, []float32{9, 10, 11, 12, 13, 14, 15})
+	if _, err := r.ReadRange(1, 4); err == nil {
+		t.Fatal("read of overwritten range succeeded")
+	}
+	if _, err := r.ReadRange(14, 20); err == nil {
+		t.Fatal("read past newest succeeded")
+	}
+
+	st = r.Stats()
+	if st.Overruns != 1 || st.Underruns != 1 {
+		t.Fatalf("stats = %+v, want 1 overrun and 1 underrun", st)
+	}
+
+	b, ok := r.Snapshot()
+	if !ok || b.Oldest != 8 || b.Newest != 15 || b.Count() != 8 {
+		t.Fatalf("snapshot = %+v, %v", b, ok)
+	}
+}
@@ -1,7 +1,51 @@
 package record
 
+import (
+	"time"
+
+	"oscilloscope/internal/trigger"
+)
+
 type Record struct {
 	Samples       []float32
 	TriggerIndex  int
 	TriggerOffset float64
+
+	// StartIndex is the absolute ring index of Samples[0].
+	StartIndex int
+	SampleRate int
+	Channel    int
+	// Time is the wall-clock time of the trigger.
+	Time time.Time
+	// Trigger is a snapshot of the trigger settings that produced the
+	// record.
+	Trigger trigger.Trigger
+	// Mode names the acquisition mode that produced the record.
+	Mode string
+	// Sequence increases by one for every record built, so a gap tells a
+	// consumer how many records it missed.
+	Sequence uint64
+
+	// Min and Max hold the extremes of each decimation bucket in
+	// peak-detect mode and are nil otherwise.
+	Min []float32
+	Max []float32
+
+	// EnvelopeMin and EnvelopeMax hold the per-sample extremes across
+	// successive records in envelope mode and are nil otherwise.
+	EnvelopeMin []float32
+	EnvelopeMax []float32
+
+	// Siblings are the records of the other input channels over the same
+	// samples, in channel order. Only the triggering channel's record
+	// carries them.
+	Siblings []Record
+}
+
+// Missed returns how many records were built between prev and r.
+func (r Record) Missed(prev Record) uint64 {
+	if r.Sequence <= prev.Sequence {
+		return 0
+	}
+	return r.Sequence - prev.Sequence - 1
 }
@@ -1,7 +1,7 @@
 package sampler
 
 import (
-	"sync"
+	"context"
 	"time"
 
 	"oscilloscope/internal/memory"
@@ -11,11 +11,10 @@ type SamplerRunner struct {
 	Sampler *Sampler
 	Ring    *memory.Ring
 
-	Cond *sync.Cond
-	Done chan struct{}
+	Notifier *memory.Notifier
 }
 
-func (r *SamplerRunner) Run() {
+func (r *SamplerRunner) Run(ctx context.Context) error {
 	stepDuration := time.Duration(r.Sampler.BufferSize()) * time.Second / time.Duration(r.Sampler.SampleRate())
 
 	ticker := time.NewTicker(stepDuration)
@@ -23,8 +22,8 @@ func (r *SamplerRunner) Run() {
 
 	for {
 		select {
-		case <-r.Done:
-			return
+		case <-ctx.Done():
+			return nil
 		case <-ticker.C:
 		}
 
@@ -35,8 +34,6 @@ func (r *SamplerRunner) Run() {
 			r.Ring.WriteAt(base+i, float32(v))
 		}
 
-		r.Cond.L.Lock()
-		r.Cond.Broadcast()
-		r.Cond.L.Unlock()
+		r.Notifier.Publish(base + len(buf) - 1)
 	}
 }
@@ -1,6 +1,9 @@
 package source
 
 const (
-	SampleRate = 44100
-	BufferSize = 128
+	DefaultSampleRate = 44100
+	DefaultBufferSize = 128
+	DefaultChannels   = 1
 )
+
+var SupportedSampleRates = []int{44100, 48000, 88200, 96000, 192000}
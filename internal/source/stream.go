@@ -0,0 +1,48 @@
+package source
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Stream describes the input stream as negotiated with the audio device.
+// Every sample-rate-dependent value is derived from it at runtime.
+type Stream struct {
+	SampleRate int
+	BufferSize int
+	// Channels is how many input channels are captured. ch1 drives the
+	// trigger and every single-channel view; the rest feed math channels.
+	Channels int
+}
+
+func DefaultStream() Stream {
+	return Stream{
+		SampleRate: DefaultSampleRate,
+		BufferSize: DefaultBufferSize,
+		Channels:   DefaultChannels,
+	}
+}
+
+func (s Stream) Validate() error {
+	if !slices.Contains(SupportedSampleRates, s.SampleRate) {
+		return fmt.Errorf("unsupported sample rate %d, want one of %v", s.SampleRate, SupportedSampleRates)
+	}
+	if s.BufferSize <= 0 {
+		return fmt.Errorf("buffer size %d must be positive", s.BufferSize)
+	}
+	if s.Channels <= 0 {
+		return fmt.Errorf("channel count %d must be positive", s.Channels)
+	}
+	return nil
+}
+
+// Samples converts a duration to a whole number of samples.
+func (s Stream) Samples(d time.Duration) int {
+	return int(d.Seconds() * float64(s.SampleRate))
+}
+
+// Duration converts a sample count to a duration.
+func (s Stream) Duration(n int) time.Duration {
+	return time.Duration(float64(n) / float64(s.SampleRate) * float64(time.Second))
+}
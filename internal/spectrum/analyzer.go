@@ -0,0 +1,192 @@
+package spectrum
+
+import (
+	"cmp"
+	"math"
+	"math/cmplx"
+	"slices"
+
+	"oscilloscope/internal/record"
+)
+
+// Frame is one spectrum. Levels[k] is the level of bin k in dBFS, where a
+// full-scale sine reads 0 dB.
+type Frame struct {
+	Levels   []float64
+	Held     []float64 // peak-hold levels, nil when peak hold is off
+	BinWidth float64   // Hz
+}
+
+// Frequency returns the centre frequency of a possibly fractional bin.
+func (f Frame) Frequency(bin float64) float64 {
+	return bin * f.BinWidth
+}
+
+type Peak struct {
+	Frequency float64
+	Level     float64
+}
+
+// Analyzer turns records into spectra. It keeps the averaging and
+// peak-hold state between records and starts over whenever its settings
+// change.
+type Analyzer struct {
+	Size     int
+	Window   Window
+	Averages int
+	PeakHold bool
+
+	size   int
+	window Window
+	span   int // samples the coefficients cover
+	coeffs []float64
+	gain   float64
+	buf    []complex128
+	power  []float64
+	held   []float64
+	count  int
+}
+
+func NewAnalyzer(size int, window Window) *Analyzer {
+	return &Analyzer{Size: size, Window: window, Averages: 1}
+}
+
+// Reset drops the averaged and held spectra.
+func (a *Analyzer) Reset() {
+	a.count = 0
+	a.held = nil
+}
+
+// Record analyses the start of rec's samples.
+func (a *Analyzer) Record(rec record.Record) Frame {
+	return a.Process(rec.Samples, rec.SampleRate)
+}
+
+// Process analyses the first Size samples. Fewer samples are windowed as
+// they are and zero-padded, so a short full-scale sine still reads 0 dB.
+func (a *Analyzer) Process(samples []float32, sampleRate int) Frame {
+	a.prepare(len(samples))
+	n := a.size
+	m := a.span
+
+	for i := range a.buf {
+		v := 0.0
+		if i < m {
+			v = float64(samples[i]) * a.coeffs[i]
+		}
+		a.buf[i] = complex(v, 0)
+	}
+	FFT(a.buf)
+
+	// Average in power so uncorrelated noise settles rather than biasing
+	// the level, weighting the first records as a plain mean
+	a.count++
+	weight := 1 / float64(min(a.count, max(a.Averages, 1)))
+	scale := 0.0
+	if a.gain > 0 {
+		scale = 1 / (float64(m) * a.gain)
+	}
+
+	levels := make([]float64, n/2+1)
+	for k := range levels {
+		amp := cmplx.Abs(a.buf[k]) * scale
+		if k > 0 && k < n/2 {
+			amp *= 2
+		}
+		a.power[k] += weight * (amp*amp - a.power[k])
+		levels[k] = toDB(a.power[k])
+	}
+
+	frame := Frame{Levels: levels, BinWidth: float64(sampleRate) / float64(n)}
+
+	if a.PeakHold {
+		if a.held == nil {
+			a.held = slices.Clone(levels)
+		}
+		for k, v := range levels {
+			a.held[k] = math.Max(a.held[k], v)
+		}
+		frame.Held = slices.Clone(a.held)
+	} else {
+		a.held = nil
+	}
+
+	return frame
+}
+
+// prepare sizes the buffers and fits the window to the n samples that will
+// be analysed, at most one FFT's worth.
+func (a *Analyzer) prepare(n int) {
+	size := a.Size
+	if size < MinSize || size > MaxSize || size&(size-1) != 0 {
+		size = DefaultSize
+	}
+	if size != a.size || a.Window != a.window || a.buf == nil {
+		a.size = size
+		a.window = a.Window
+		a.span = -1
+		a.buf = make([]complex128, size)
+		a.power = make([]float64, size/2+1)
+		a.Reset()
+	}
+
+	if span := min(n, size); span != a.span {
+		a.span = span
+		a.coeffs = a.Window.Coefficients(span)
+		a.gain = coherentGain(a.coeffs)
+	}
+}
+
+// Peaks returns up to n local maxima of levels, loudest first. The
+// frequency is refined between bins by fitting a parabola through each
+// maximum and its neighbours; the level is that of the loudest bin, which
+// is exact between bins only with the FlatTop window.
+func Peaks(f Frame, n int) []Peak {
+	l := f.Levels
+
+	var candidates []int
+	for k := 1; k < len(l)-1; k++ {
+		if l[k] > Floor && l[k] >= l[k-1] && l[k] > l[k+1] {
+			candidates = append(candidates, k)
+		}
+	}
+	slices.SortFunc(candidates, func(a, b int) int { return cmp.Compare(l[b], l[a]) })
+
+	var peaks []Peak
+	var taken []int
+	for _, k := range candidates {
+		if len(peaks) == n {
+			break
+		}
+		if slices.ContainsFunc(taken, func(t int) bool { return abs(t-k) < peakSpacing }) {
+			continue
+		}
+		taken = append(taken, k)
+
+		alpha, beta, gamma := l[k-1], l[k], l[k+1]
+		p := 0.0
+		if d := alpha - 2*beta + gamma; d != 0 {
+			p = 0.5 * (alpha - gamma) / d
+		}
+
+		peaks = append(peaks, Peak{
+			Frequency: f.Frequency(float64(k) + p),
+			Level:     beta,
+		})
+	}
+	return peaks
+}
+
+func toDB(power float64) float64 {
+	if power <= 0 {
+		return Floor
+	}
+	return math.Max(10*math.Log10(power), Floor)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
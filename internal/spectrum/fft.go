@@ -0,0 +1,43 @@
+package spectrum
+
+import (
+	"math"
+	"math/bits"
+)
+
+// FFT transforms x in place with an iterative radix-2 Cooley-Tukey
+// algorithm. len(x) must be a power of two.
+func FFT(x []complex128) {
+	n := len(x)
+	if n <= 1 {
+		return
+	}
+	if n&(n-1) != 0 {
+		panic("spectrum: FFT length is not a power of two")
+	}
+
+	// Bit-reversal permutation
+	shift := 64 - bits.TrailingZeros(uint(n))
+	for i := range n {
+		j := int(bits.Reverse64(uint64(i)) >> shift)
+		if j > i {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		half := size / 2
+		step := -2 * math.Pi / float64(size)
+		for start := 0; start < n; start += size {
+			for k := range half {
+				s, c := math.Sincos(step * float64(k))
+				w := complex(c, s)
+
+				a := x[start+k]
+				b := w * x[start+k+half]
+				x[start+k] = a + b
+				x[start+k+half] = a - b
+			}
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package spectrum
+
+const (
+	DefaultSize = 4096
+	MinSize     = 256
+	MaxSize     = 65536
+
+	// Floor is the lowest level reported, in dBFS, so silence stays finite.
+	Floor = -160.0
+
+	// peakSpacing is the minimum distance in bins between two reported
+	// peaks, wide enough that one tone's main lobe is not reported twice.
+	peakSpacing = 4
+)
+
+type Window int
+
+const (
+	Hann Window = iota
+	Hamming
+	BlackmanHarris
+	// FlatTop trades frequency resolution for amplitude accuracy between
+	// bins.
+	FlatTop
+	windowCount
+)
+
+func (w Window) String() string {
+	switch w {
+	case Hamming:
+		return "hamming"
+	case BlackmanHarris:
+		return "blackman-harris"
+	case FlatTop:
+		return "flat-top"
+	default:
+		return "hann"
+	}
+}
+
+// Next returns the window after w, wrapping around.
+func (w Window) Next() Window {
+	return (w + 1) % windowCount
+}
+
+// cosine sum coefficients a0, a1, ... of each window.
+var windowTerms = [windowCount][]float64{
+	Hann:           {0.5, 0.5},
+	Hamming:        {0.54, 0.46},
+	BlackmanHarris: {0.35875, 0.48829, 0.14128, 0.01168},
+	FlatTop:        {0.21557895, 0.41663158, 0.277263158, 0.083578947, 0.006947368},
+}
@@ -0,0 +1,141 @@
+package spectrum
+
+import (
+	"math"
+	"math/cmplx"
+	"math/rand/v2"
+	"testing"
+)
+
+const testRate = 48000
+
+func tone(n int, freq, amp float64) []float32 {
+	s := make([]float32, n)
+	for i := range s {
+		s[i] = float32(amp * math.Sin(2*math.Pi*freq*float64(i)/testRate))
+	}
+	return s
+}
+
+func TestFFTMatchesDFT(t *testing.T) {
+	const n = 64
+	r := rand.New(rand.NewPCG(1, 2))
+
+	x := make([]complex128, n)
+	for i := range x {
+		x[i] = complex(r.Float64()*2-1, r.Float64()*2-1)
+	}
+
+	want := make([]complex128, n)
+	for k := range want {
+		for i, v := range x {
+			want[k] += v * cmplx.Exp(complex(0, -2*math.Pi*float64(k*i)/n))
+		}
+	}
+
+	FFT(x)
+	for k := range x {
+		if cmplx.Abs(x[k]-want[k]) > 1e-9 {
+			t.Fatalf("bin %d = %v, want %v", k, x[k], want[k])
+		}
+	}
+}
+
+func TestToneOnBinReadsItsAmplitude(t *testing.T) {
+	const size = 4096
+	binWidth := float64(testRate) / size
+	freq := 100 * binWidth
+	want := 20 * math.Log10(0.5)
+
+	for w := range windowCount {
+		t.Run(w.String(), func(t *testing.T) {
+			frame := NewAnalyzer(size, w).Process(tone(size, freq, 0.5), testRate)
+
+			if got := frame.Levels[100]; math.Abs(got-want) > 0.01 {
+				t.Fatalf("level = %.3f dBFS, want %.3f", got, want)
+			}
+			if frame.BinWidth != binWidth {
+				t.Fatalf("bin width = %g, want %g", frame.BinWidth, binWidth)
+			}
+		})
+	}
+}
+
+func TestFlatTopBetweenBins(t *testing.T) {
+	const size = 4096
+	freq := 100.5 * testRate / size
+	want := 20 * math.Log10(0.5)
+
+	frame := NewAnalyzer(size, FlatTop).Process(tone(size, freq, 0.5), testRate)
+
+	peaks := Peaks(frame, 1)
+	if len(peaks) != 1 {
+		t.Fatalf("got %d peaks, want 1", len(peaks))
+	}
+	if math.Abs(peaks[0].Level-want) > 0.05 {
+		t.Fatalf("level = %.3f dBFS, want %.3f", peaks[0].Level, want)
+	}
+}
+
+func TestPeaksFindsTones(t *testing.T) {
+	const size = 8192
+	a := tone(size, 440, 0.5)
+	b := tone(size, 3000, 0.1)
+	for i := range a {
+		a[i] += b[i]
+	}
+
+	frame := NewAnalyzer(size, BlackmanHarris).Process(a, testRate)
+	peaks := Peaks(frame, 2)
+	if len(peaks) != 2 {
+		t.Fatalf("got %d peaks, want 2", len(peaks))
+	}
+
+	for i, want := range []float64{440, 3000} {
+		if math.Abs(peaks[i].Frequency-want) > 0.1*frame.BinWidth {
+			t.Errorf("peak %d at %.2f Hz, want %.2f", i, peaks[i].Frequency, want)
+		}
+	}
+}
+
+func TestAveragingAndPeakHold(t *testing.T) {
+	const size = 1024
+	a := NewAnalyzer(size, Hann)
+	a.Averages = 2
+	a.PeakHold = true
+
+	loud := tone(size, 100*float64(testRate)/size, 0.5)
+	quiet := tone(size, 100*float64(testRate)/size, 0.25)
+
+	a.Process(loud, testRate)
+	frame := a.Process(quiet, testRate)
+
+	// The mean power of 0.5 and 0.25 amplitude tones
+	want := 10 * math.Log10((0.25+0.0625)/2)
+	if got := frame.Levels[100]; math.Abs(got-want) > 0.01 {
+		t.Fatalf("averaged level = %.3f, want %.3f", got, want)
+	}
+	if got := frame.Held[100]; math.Abs(got-20*math.Log10(0.5)) > 0.01 {
+		t.Fatalf("held level = %.3f, want the louder tone", got)
+	}
+
+	a.Window = Hamming
+	frame = a.Process(quiet, testRate)
+	if got := frame.Levels[100]; math.Abs(got-20*math.Log10(0.25)) > 0.01 {
+		t.Fatalf("level after a settings change = %.3f, want the new record alone", got)
+	}
+}
+
+func TestShortInputIsZeroPadded(t *testing.T) {
+	const size = 4096
+	freq := 400 * float64(testRate) / size
+	want := 20 * math.Log10(0.5)
+
+	frame := NewAnalyzer(size, Hann).Process(tone(300, freq, 0.5), testRate)
+	if len(frame.Levels) != size/2+1 {
+		t.Fatalf("got %d levels, want %d", len(frame.Levels), size/2+1)
+	}
+	if got := frame.Levels[400]; math.Abs(got-want) > 0.1 {
+		t.Fatalf("level = %.3f dBFS, want %.3f", got, want)
+	}
+}
@@ -0,0 +1,33 @@
+package spectrum
+
+import "math"
+
+// Coefficients returns the periodic form of the window for an n-point FFT.
+func (w Window) Coefficients(n int) []float64 {
+	terms := windowTerms[Hann]
+	if w >= 0 && w < windowCount {
+		terms = windowTerms[w]
+	}
+
+	out := make([]float64, n)
+	for i := range out {
+		v := 0.0
+		sign := 1.0
+		for k, a := range terms {
+			v += sign * a * math.Cos(2*math.Pi*float64(k)*float64(i)/float64(n))
+			sign = -sign
+		}
+		out[i] = v
+	}
+	return out
+}
+
+// coherentGain is the mean of the window, the factor a tone's amplitude is
+// scaled by.
+func coherentGain(coeffs []float64) float64 {
+	var sum float64
+	for _, c := range coeffs {
+		sum += c
+	}
+	return sum / float64(len(coeffs))
+}
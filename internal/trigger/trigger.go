@@ -13,8 +13,12 @@ type Result struct {
 
 type Trigger struct {
 	Polarity Polarity
-	Lower    float64
-	Upper    float64
+	// Level is the value a rising sample must cross to trigger; Lower and
+	// Upper are absolute hysteresis thresholds around it. Negative
+	// polarity mirrors the thresholds about Level, see Band.
+	Level float64
+	Lower float64
+	Upper float64
 }
 
 func New() *Trigger {
@@ -25,56 +29,88 @@ func New() *Trigger {
 	}
 }
 
+// Band returns the arming threshold and the disarming threshold. The
+// signal must fall to lower before a crossing of Level counts, and rising
+// to upper without one disarms it.
+func (t *Trigger) Band() (lower, upper float64) {
+	dir := float64(t.Polarity)
+
+	l := t.Level + dir*(t.Lower-t.Level)
+	u := t.Level + dir*(t.Upper-t.Level)
+
+	return math.Min(l, u), math.Max(l, u)
+}
+
 func (t *Trigger) Find(
 	ring *memory.Ring,
 	start int,
 	end int,
 ) (Result, bool) {
+	res, _, ok := t.FindResume(ring, start, end)
+	return res, ok
+}
+
+// FindResume is Find that also returns, when no trigger is found, the index
+// a later search can start from and still see the same arming state: the
+// point the trigger last armed at, or the end of the range if it is not
+// armed.
+func (t *Trigger) FindResume(
+	ring *memory.Ring,
+	start int,
+	end int,
+) (Result, int, bool) {
 	if end <= start+1 {
-		return Result{}, false
+		return Result{}, start, false
 	}
 
-	dir := float64(t.Polarity)
+	lower, upper := t.Band()
 
-	l := dir * t.Lower
-	u := dir * t.Upper
-
-	lower := math.Min(l, u)
-	upper := math.Max(l, u)
-
-	// Bulk read from ring buffer (single lock acquisition)
-	samples, err := ring.ReadRange(start, end)
-	if err != nil {
-		return Result{}, false
+	// Read the ring in place; the window is validated before any result
+	// is returned
+	samples, ok := ring.View(start, end)
+	if !ok {
+		return Result{}, start, false
 	}
 
 	armed := false
+	armedAt := 0
 
-	for i := 1; i < len(samples); i++ {
-		prev := float64(samples[i-1])
-		curr := float64(samples[i])
+	for i := 1; i < samples.Len(); i++ {
+		prev := float64(samples.At(i - 1))
+		curr := float64(samples.At(i))
 
-		if !armed {
-			if prev <= lower {
-				armed = true
-			}
-		} else {
-			if curr >= upper {
-				armed = false
-			}
+		if !armed && prev <= lower {
+			armed = true
+			armedAt = i - 1
 		}
 
-		// Gate zero-crossing on armed state for correct hysteresis
-		if armed && prev < 0 && curr >= 0 {
+		// Gate level crossing on armed state for correct hysteresis. The
+		// crossing is checked before disarming, so an edge that clears
+		// the whole band in one sample still triggers.
+		if armed && prev < t.Level && curr >= t.Level {
 			slope := curr - prev
-			offset := -prev / slope
+			offset := (t.Level - prev) / slope
+
+			if !samples.Valid() {
+				return Result{}, start, false
+			}
 
 			return Result{
 				Index:  start + i - 1, // Map back to ring buffer index
 				Offset: offset,
-			}, true
+			}, 0, true
+		}
+
+		if armed && curr >= upper {
+			armed = false
 		}
 	}
 
-	return Result{}, false
+	if !samples.Valid() {
+		return Result{}, start, false
+	}
+	if armed {
+		return Result{}, start + armedAt, false
+	}
+	return Result{}, end - 1, false
 }
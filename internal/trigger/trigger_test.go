@@ -0,0 +1,84 @@
+package trigger
+
+import (
+	"math"
+	"testing"
+
+	"oscilloscope/internal/memory"
+)
+
+func BenchmarkFind(b *testing.B) {
+	ring := memory.New(memory.MemoryBufferSize)
+	buf := make([]float32, memory.MemoryBufferSize)
+	for i := range buf {
+		buf[i] = float32(math.Sin(2 * math.Pi * float64(i) / 4096))
+	}
+	ring.WriteBatch(0, buf)
+
+	t := New()
+	start := ring.OldestIndex() + 1024 // past the rising crossing at 0, so the search covers most of the ring
+
+	b.ReportAllocs()
+	for b.Loop() {
+		t.Find(ring, start, ring.NewestIndex())
+	}
+}
+
+func TestFindStepEdge(t *testing.T) {
+	ring := memory.New(64)
+	buf := make([]float32, 64)
+	for i := range buf {
+		buf[i] = -0.5
+		if i >= 20 {
+			buf[i] = 0.5
+		}
+	}
+	ring.WriteBatch(0, buf)
+
+	res, ok := New().Find(ring, 0, 63)
+	if !ok {
+		t.Fatal("an edge that clears the hysteresis band in one sample did not trigger")
+	}
+	if res.Index != 19 || res.Offset != 0.5 {
+		t.Fatalf("got %+v, want index 19 offset 0.5", res)
+	}
+}
+
+func TestBandMirrorsAboutLevel(t *testing.T) {
+	pos := Trigger{Polarity: Positive, Level: 0.3, Lower: 0.2, Upper: 0.5}
+	if l, u := pos.Band(); l != 0.2 || u != 0.5 {
+		t.Fatalf("positive band = %g, %g, want 0.2, 0.5", l, u)
+	}
+
+	neg := pos
+	neg.Polarity = Negative
+	l, u := neg.Band()
+	if math.Abs(l-0.1) > 1e-12 || math.Abs(u-0.4) > 1e-12 {
+		t.Fatalf("negative band = %g, %g, want 0.1, 0.4", l, u)
+	}
+}
+
+func TestFindLevel(t *testing.T) {
+	ring := memory.New(256)
+	buf := make([]float32, 256)
+	for i := range buf {
+		buf[i] = float32(0.3 + 0.5*math.Sin(2*math.Pi*float64(i)/64))
+	}
+	ring.WriteBatch(0, buf)
+
+	// The band is symmetric about the level, so both polarities arm the
+	// same way
+	for _, p := range []Polarity{Positive, Negative} {
+		trig := &Trigger{Polarity: p, Level: 0.3, Lower: 0.2, Upper: 0.4}
+		res, ok := trig.Find(ring, 1, 255)
+		if !ok {
+			t.Fatalf("polarity %d: no trigger", p)
+		}
+
+		// The first armed rising crossing of 0.3 is at the start of the
+		// second cycle
+		if at := float64(res.Index) + res.Offset; math.Abs(at-64) > 0.01 {
+			t.Fatalf("polarity %d: triggered at %.3f, want 64", p, at)
+		}
+	}
+}